@@ -0,0 +1,64 @@
+package portunes
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+)
+
+// ErrUnknownUser is returned from UserStore.LookupHash when
+// the user does not exist.
+var ErrUnknownUser = errors.New("portunes: unknown user")
+
+// UserStore is implemented by stores of user password
+// hashes for use with Client.Authenticate.
+type UserStore interface {
+	// LookupHash returns the hash previously stored for
+	// user. It must return an error for which
+	// errors.Is(err, ErrUnknownUser) is true if the user
+	// does not exist.
+	LookupHash(ctx context.Context, user string) ([]byte, error)
+}
+
+// RehashFunc persists newHash as the hash for user. It
+// should only replace the stored hash if it is still
+// oldHash, as it may have been changed concurrently.
+type RehashFunc func(ctx context.Context, user string, oldHash, newHash []byte) error
+
+// Authenticate looks up the hash for user in store and
+// verifies password and pepper against it.
+//
+// If the user does not exist, the password is checked with
+// VerifyDummy so that the time taken doesn't reveal whether
+// the user exists, and valid will be false.
+//
+// If the password is valid and the hash should be rehashed,
+// a new hash is derived and passed to rehash, which may be
+// nil. If this fails, valid will still be true and the error
+// will be returned so the caller can decide whether to
+// allow the login.
+//
+// opts can be used to provide grpc.CallOption's to the
+// underlying connection.
+func (c *Client) Authenticate(ctx context.Context, store UserStore, user, password string, pepper []byte, rehash RehashFunc, opts ...grpc.CallOption) (valid bool, err error) {
+	hash, err := store.LookupHash(ctx, user)
+	if errors.Is(err, ErrUnknownUser) {
+		return false, c.VerifyDummy(ctx, password, pepper, opts...)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	valid, shouldRehash, err := c.Verify(ctx, password, pepper, hash, opts...)
+	if err != nil || !valid || !shouldRehash || rehash == nil {
+		return valid, err
+	}
+
+	newHash, err := c.Hash(ctx, password, pepper, opts...)
+	if err != nil {
+		return true, err
+	}
+
+	return true, rehash(ctx, user, hash, newHash)
+}
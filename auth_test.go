@@ -0,0 +1,83 @@
+package portunes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapStore struct {
+	mu     sync.Mutex
+	hashes map[string][]byte
+}
+
+func (s *mapStore) LookupHash(ctx context.Context, user string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash, ok := s.hashes[user]
+	if !ok {
+		return nil, ErrUnknownUser
+	}
+
+	return hash, nil
+}
+
+func (s *mapStore) rehash(ctx context.Context, user string, oldHash, newHash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hashes[user] = newHash
+	return nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	c, s, stop := testingClient()
+	defer stop()
+
+	s.SetParameters(1, 32*1024, 1)
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	s.SetParameters(1, 64*1024, 1)
+
+	store := &mapStore{hashes: map[string][]byte{"alice": hash}}
+
+	valid, err := c.Authenticate(context.Background(), store, "alice", "wrong", []byte("🔑📋"), store.rehash)
+	require.NoError(t, err)
+	assert.False(t, valid, "valid")
+	assert.Equal(t, hash, store.hashes["alice"], "rehashed invalid password")
+
+	valid, err = c.Authenticate(context.Background(), store, "mallory", "password🔐🔓", []byte("🔑📋"), store.rehash)
+	require.NoError(t, err)
+	assert.False(t, valid, "valid")
+
+	valid, err = c.Authenticate(context.Background(), store, "alice", "password🔐🔓", []byte("🔑📋"), nil)
+	require.NoError(t, err)
+	assert.True(t, valid, "valid")
+	assert.Equal(t, hash, store.hashes["alice"], "rehashed without RehashFunc")
+
+	valid, err = c.Authenticate(context.Background(), store, "alice", "password🔐🔓", []byte("🔑📋"), store.rehash)
+	require.NoError(t, err)
+	assert.True(t, valid, "valid")
+	assert.NotEqual(t, hash, store.hashes["alice"], "not rehashed")
+
+	valid, _, err = c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), store.hashes["alice"])
+	require.NoError(t, err)
+	assert.True(t, valid, "new hash valid")
+
+	errRehash := errors.New("rehash failed")
+	store.hashes["alice"] = hash
+
+	valid, err = c.Authenticate(context.Background(), store, "alice", "password🔐🔓", []byte("🔑📋"),
+		func(context.Context, string, []byte, []byte) error { return errRehash })
+	assert.Equal(t, errRehash, err)
+	assert.True(t, valid, "valid")
+}
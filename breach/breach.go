@@ -0,0 +1,232 @@
+// Package breach implements a compact, probabilistic set of
+// breached passwords.
+//
+// A Filter is a bloom filter keyed by the SHA-1 hash of each
+// password, as published by the Have I Been Pwned Pwned
+// Passwords list[1]. It never reports a breached password as
+// safe, but may report a safe password as breached with a
+// configurable false positive rate.
+//
+// [1] https://haveibeenpwned.com/Passwords
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+var magic = [8]byte{'p', 'o', 'r', 't', 'b', 'f', 0, 1}
+
+// Filter is a set of breached passwords.
+type Filter struct {
+	k    uint32
+	bits []uint64
+}
+
+// New returns an empty Filter sized to hold n passwords
+// with the given false positive rate.
+func New(n uint64, fpRate float64) *Filter {
+	if n == 0 {
+		n = 1
+	}
+
+	if fpRate <= 0 || fpRate >= 1 {
+		panic("breach: invalid false positive rate")
+	}
+
+	m := math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(n) * math.Ln2)
+	if k < 1 {
+		k = 1
+	}
+
+	return &Filter{
+		k:    uint32(k),
+		bits: make([]uint64, (uint64(m)+63)/64),
+	}
+}
+
+// indexes calls fn with each bit index for sum. As sum is a
+// cryptographic hash, it is split into two independent
+// hashes and combined with double hashing.
+func (f *Filter) indexes(sum *[sha1.Size]byte, fn func(i uint64) bool) bool {
+	m := uint64(len(f.bits)) * 64
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+
+	for i := uint32(0); i < f.k; i++ {
+		if !fn((h1 + uint64(i)*h2) % m) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AddSHA1 adds the password with the given SHA-1 hash to
+// the Filter.
+func (f *Filter) AddSHA1(sum [sha1.Size]byte) {
+	f.indexes(&sum, func(i uint64) bool {
+		f.bits[i/64] |= 1 << (i % 64)
+		return true
+	})
+}
+
+// ContainsSHA1 reports whether the password with the given
+// SHA-1 hash may be in the Filter.
+func (f *Filter) ContainsSHA1(sum [sha1.Size]byte) bool {
+	return f.indexes(&sum, func(i uint64) bool {
+		return f.bits[i/64]&(1<<(i%64)) != 0
+	})
+}
+
+// Contains reports whether password may be in the Filter.
+func (f *Filter) Contains(password string) bool {
+	return f.ContainsSHA1(sha1.Sum([]byte(password)))
+}
+
+// AddHashes reads SHA-1 hashes from r and adds them to the
+// Filter. Each line must contain a hex encoded SHA-1 hash,
+// optionally followed by a colon and a count, as in the
+// Pwned Passwords list. Blank lines are ignored.
+func (f *Filter) AddHashes(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" {
+			continue
+		}
+
+		if idx := strings.IndexByte(text, ':'); idx >= 0 {
+			text = text[:idx]
+		}
+
+		var sum [sha1.Size]byte
+		if hex.DecodedLen(len(text)) != len(sum) {
+			return fmt.Errorf("breach: invalid SHA-1 hash on line %d", line)
+		}
+
+		if _, err := hex.Decode(sum[:], []byte(text)); err != nil {
+			return fmt.Errorf("breach: invalid SHA-1 hash on line %d: %v", line, err)
+		}
+
+		f.AddSHA1(sum)
+	}
+
+	return s.Err()
+}
+
+// WriteTo writes the Filter to w in a form that can be
+// read by Read.
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	var hdr [len(magic) + 4 + 8]byte
+	copy(hdr[:], magic[:])
+	binary.BigEndian.PutUint32(hdr[len(magic):], f.k)
+	binary.BigEndian.PutUint64(hdr[len(magic)+4:], uint64(len(f.bits)))
+
+	if _, err := bw.Write(hdr[:]); err != nil {
+		return cw.n, err
+	}
+
+	var buf [8]byte
+	for _, word := range f.bits {
+		binary.LittleEndian.PutUint64(buf[:], word)
+		if _, err := bw.Write(buf[:]); err != nil {
+			return cw.n, err
+		}
+	}
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// countWriter counts the bytes written to w, so that WriteTo
+// reports what reached w rather than what was buffered.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+var errInvalidFilter = errors.New("breach: invalid filter")
+
+// readChunkWords is the number of words Read allocates
+// before any of them have been read.
+const readChunkWords = 64 << 10
+
+// Read reads a Filter previously written with WriteTo.
+func Read(r io.Reader) (*Filter, error) {
+	br := bufio.NewReader(r)
+
+	var hdr [len(magic) + 4 + 8]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	var m [len(magic)]byte
+	copy(m[:], hdr[:])
+	if m != magic {
+		return nil, errInvalidFilter
+	}
+
+	k := binary.BigEndian.Uint32(hdr[len(magic):])
+	words := binary.BigEndian.Uint64(hdr[len(magic)+4:])
+	if k == 0 || words == 0 || words > math.MaxInt32 {
+		return nil, errInvalidFilter
+	}
+
+	// The bits are grown as they're read, rather than
+	// allocated up front, so that a corrupt header can't
+	// cause a huge allocation before the data runs out.
+	initial := words
+	if initial > readChunkWords {
+		initial = readChunkWords
+	}
+
+	f := &Filter{
+		k:    k,
+		bits: make([]uint64, 0, initial),
+	}
+
+	var buf [8]byte
+	for i := uint64(0); i < words; i++ {
+		if _, err := io.ReadFull(br, buf[:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return nil, err
+		}
+
+		f.bits = append(f.bits, binary.LittleEndian.Uint64(buf[:]))
+	}
+
+	return f, nil
+}
+
+// Load reads a Filter from the named file.
+func Load(name string) (*Filter, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
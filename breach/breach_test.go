@@ -0,0 +1,131 @@
+package breach
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	const n = 10000
+
+	f := New(n, 0.01)
+
+	for i := 0; i < n; i++ {
+		f.AddSHA1(sha1.Sum([]byte(fmt.Sprintf("password%d", i))))
+	}
+
+	for i := 0; i < n; i++ {
+		assert.True(t, f.Contains(fmt.Sprintf("password%d", i)), "false negative")
+	}
+
+	var fp int
+	for i := 0; i < n; i++ {
+		if f.Contains(fmt.Sprintf("safe%d", i)) {
+			fp++
+		}
+	}
+
+	assert.InDelta(t, 0.01, float64(fp)/n, 0.01, "false positive rate")
+}
+
+func TestAddHashes(t *testing.T) {
+	t.Parallel()
+
+	f := New(3, 0.0001)
+	require.NoError(t, f.AddHashes(strings.NewReader(
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n"+
+			"\n"+
+			"7c4a8d09ca3762af61e59520943dc26494f8941b\n")))
+
+	assert.True(t, f.Contains("password"))
+	assert.True(t, f.Contains("123456"))
+	assert.False(t, f.Contains("correct horse battery staple"))
+
+	assert.Error(t, f.AddHashes(strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE68F:1\n")))
+	assert.Error(t, f.AddHashes(strings.NewReader("ZBAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\n")))
+}
+
+func TestReadWrite(t *testing.T) {
+	t.Parallel()
+
+	f := New(100, 0.001)
+	f.AddSHA1(sha1.Sum([]byte("password")))
+
+	var buf bytes.Buffer
+	n, err := f.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	f2, err := Read(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, f, f2)
+
+	_, err = Read(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.Error(t, err)
+
+	buf.Bytes()[0] ^= 0xff
+	_, err = Read(bytes.NewReader(buf.Bytes()))
+	assert.Error(t, err)
+}
+
+// limitWriter accepts n bytes and then fails.
+type limitWriter struct{ n int }
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errors.New("short write")
+	}
+
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestWriteToPartial(t *testing.T) {
+	t.Parallel()
+
+	f := New(100000, 0.001)
+
+	for _, limit := range []int{0, 10, 4096, 4100, 10000} {
+		n, err := f.WriteTo(&limitWriter{limit})
+		assert.Error(t, err)
+		assert.Equal(t, int64(limit), n, "limit %d", limit)
+	}
+}
+
+func TestReadHugeHeader(t *testing.T) {
+	// Not parallel so that other tests don't count towards
+	// the bytes allocated.
+
+	var buf bytes.Buffer
+	_, err := New(100, 0.001).WriteTo(&buf)
+	require.NoError(t, err)
+
+	// Claim math.MaxInt32 words, which would be 16GiB, but
+	// only provide the original few.
+	hdr := buf.Bytes()[len(magic)+4:]
+	binary.BigEndian.PutUint64(hdr, math.MaxInt32)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	_, err = Read(bytes.NewReader(buf.Bytes()))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	runtime.ReadMemStats(&after)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(16<<20), "bytes allocated")
+}
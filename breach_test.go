@@ -0,0 +1,61 @@
+package portunes
+
+import (
+	"context"
+	"crypto/sha1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/portunes/breach"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreachFilter(t *testing.T) {
+	t.Parallel()
+
+	f := breach.New(1, 0.0001)
+	f.AddSHA1(sha1.Sum([]byte("password")))
+
+	c, _, stop := testingClient(WithBreachFilter(f))
+	defer stop()
+
+	_, err := c.Hash(context.Background(), "password", []byte("🔑📋"))
+	require.Error(t, err)
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	valid, _, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+}
+
+func TestBreachFilterNormalized(t *testing.T) {
+	t.Parallel()
+
+	f := breach.New(1, 0.0001)
+	f.AddSHA1(sha1.Sum([]byte("password")))
+
+	c, _, stop := testingClient(WithBreachFilter(f), WithNormalization(NormalizeNFKC))
+	defer stop()
+
+	// The fullwidth spelling normalizes to "password" under
+	// NFKC.
+	_, err := c.Hash(context.Background(), "ｐａｓｓｗｏｒｄ", []byte("🔑📋"))
+	require.Error(t, err)
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+
+	violations, _, err := c.CheckPolicy(context.Background(), "ｐａｓｓｗｏｒｄ", nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, violations, PolicyViolation{
+		Reason:  PolicyBreached,
+		Message: "password is known to be breached",
+	})
+}
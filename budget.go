@@ -0,0 +1,164 @@
+package portunes
+
+import (
+	"context"
+	"sync"
+
+	"go.tmthrgd.dev/portunes/internal/argon2"
+	"google.golang.org/grpc/status"
+)
+
+// memoryBudget hands out argon2 memory while keeping the
+// total allocated, both in use and idle, within a budget.
+// Memory returned with put is reused by later requests for
+// the same number of blocks, so that steady traffic at the
+// server's parameters doesn't allocate.
+type memoryBudget struct {
+	mu sync.Mutex
+
+	budget    uint64 // in 1 KiB blocks
+	allocated uint64 // in 1 KiB blocks, including idle
+
+	idle    []*argon2.Memory // oldest first
+	waiters []*budgetWaiter  // in arrival order
+}
+
+type budgetWaiter struct {
+	blocks uint32
+
+	// mem is the memory granted to the waiter, or nil if
+	// it must allocate the memory it was granted.
+	mem   *argon2.Memory
+	ready chan struct{}
+}
+
+func newMemoryBudget(kib uint64) *memoryBudget {
+	return &memoryBudget{budget: kib}
+}
+
+// fits reports whether the given number of blocks fit
+// within the budget at all.
+func (mb *memoryBudget) fits(blocks uint32) bool {
+	return uint64(blocks) <= mb.budget
+}
+
+// get returns memory of the given number of blocks, waiting
+// until it fits within the budget or ctx is done. Waiters
+// are served in order so that large requests aren't starved
+// by small ones. The blocks must fit within the budget.
+func (mb *memoryBudget) get(ctx context.Context, blocks uint32) (*argon2.Memory, error) {
+	mb.mu.Lock()
+
+	if len(mb.waiters) == 0 {
+		if mem, ok := mb.tryGetLocked(blocks); ok {
+			mb.mu.Unlock()
+			return allocMemory(mem, blocks), nil
+		}
+	}
+
+	w := &budgetWaiter{blocks: blocks, ready: make(chan struct{})}
+	mb.waiters = append(mb.waiters, w)
+	mb.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return allocMemory(w.mem, blocks), nil
+	case <-ctx.Done():
+	}
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		// The memory was granted as ctx was done, so give
+		// it back for the next waiter.
+		if w.mem == nil {
+			mb.allocated -= uint64(blocks)
+		} else {
+			mb.idle = append(mb.idle, w.mem)
+		}
+	default:
+		for i, ww := range mb.waiters {
+			if ww == w {
+				mb.waiters = append(mb.waiters[:i], mb.waiters[i+1:]...)
+				break
+			}
+		}
+	}
+
+	mb.wakeLocked()
+	return nil, status.FromContextError(ctx.Err()).Err()
+}
+
+// put returns memory from get to be reused.
+func (mb *memoryBudget) put(mem *argon2.Memory) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	mb.idle = append(mb.idle, mem)
+	mb.wakeLocked()
+}
+
+// tryGetLocked takes idle memory of the given number of
+// blocks if there is any. Otherwise, if the blocks would fit
+// within the budget without the idle memory, it discards
+// idle memory, oldest first, until they do and returns nil
+// with ok set, in which case the caller must allocate the
+// memory. It returns false if the blocks don't fit.
+func (mb *memoryBudget) tryGetLocked(blocks uint32) (mem *argon2.Memory, ok bool) {
+	for i, mem := range mb.idle {
+		if mem.Len() == blocks {
+			mb.idle = append(mb.idle[:i], mb.idle[i+1:]...)
+			return mem, true
+		}
+	}
+
+	inUse := mb.allocated
+	for _, mem := range mb.idle {
+		inUse -= uint64(mem.Len())
+	}
+
+	if inUse+uint64(blocks) > mb.budget {
+		return nil, false
+	}
+
+	for mb.allocated+uint64(blocks) > mb.budget {
+		mb.allocated -= uint64(mb.idle[0].Len())
+		mb.idle[0] = nil
+		mb.idle = mb.idle[1:]
+	}
+
+	mb.allocated += uint64(blocks)
+	return nil, true
+}
+
+// wakeLocked grants memory to as many waiters, in order, as
+// fit within the budget.
+func (mb *memoryBudget) wakeLocked() {
+	for len(mb.waiters) > 0 {
+		w := mb.waiters[0]
+
+		mem, ok := mb.tryGetLocked(w.blocks)
+		if !ok {
+			return
+		}
+
+		w.mem = mem
+		close(w.ready)
+
+		mb.waiters[0] = nil
+		mb.waiters = mb.waiters[1:]
+	}
+}
+
+// allocMemory returns mem or, if it's nil, new memory. The
+// memory is allocated without holding the lock as zeroing
+// it can take some time.
+func allocMemory(mem *argon2.Memory, blocks uint32) *argon2.Memory {
+	if mem != nil {
+		return mem
+	}
+
+	return argon2.NewMemory(blocks)
+}
@@ -0,0 +1,214 @@
+package portunes
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.tmthrgd.dev/portunes/internal/argon2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	t.Parallel()
+
+	for _, opts := range [][]ServerOption{
+		{WithMemoryBudget(64 * 1024)},
+		{WithMemoryBudget(64 * 1024), WithHardenedMemory()},
+	} {
+		c, s, stop := testingClient(opts...)
+		defer stop()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+				if assert.NoError(t, err) {
+					assert.True(t, valid, "valid")
+					assert.False(t, rehash, "rehash")
+				}
+
+				assert.NoError(t, c.VerifyDummy(context.Background(), "password🔐🔓", []byte("🔑📋")))
+			}()
+		}
+		wg.Wait()
+
+		wrapped, err := c.WrapLegacy(context.Background(), []byte("$apr1$8sFt66rZ$/2KB/ChEot7Ge/1n068od/"), []byte("🔑📋"))
+		require.NoError(t, err)
+
+		valid, _, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), wrapped)
+		require.NoError(t, err)
+		assert.True(t, valid, "valid")
+
+		// All the memory should be idle and reusable.
+		assert.Equal(t, uint64(64*1024), s.memory.allocated, "allocated")
+		require.Len(t, s.memory.idle, 1)
+		assert.Empty(t, s.memory.waiters, "waiters")
+
+		if s.hardened {
+			mem := s.memory.idle[0]
+			zero := argon2.NewMemory(mem.Len())
+			assert.Equal(t, zero, mem, "idle memory not zeroed")
+		}
+	}
+}
+
+func TestMemoryBudgetTooLarge(t *testing.T) {
+	t.Parallel()
+
+	c, s, stop := testingClient(WithMemoryBudget(32 * 1024))
+	defer stop()
+
+	_, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "invalid gRPC status code")
+
+	s.SetParameters(1, 32*1024, 2)
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	valid, _, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+	assert.True(t, valid, "valid")
+}
+
+func TestMemoryBudgetWait(t *testing.T) {
+	t.Parallel()
+
+	mb := newMemoryBudget(64)
+
+	a, err := mb.get(context.Background(), 32)
+	require.NoError(t, err)
+	b, err := mb.get(context.Background(), 32)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = mb.get(ctx, 32)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err), "invalid gRPC status code")
+	assert.Empty(t, mb.waiters, "waiters")
+
+	got := make(chan *argon2.Memory)
+	go func() {
+		mem, err := mb.get(context.Background(), 32)
+		assert.NoError(t, err)
+		got <- mem
+	}()
+
+	mb.put(a)
+	assert.Same(t, a, <-got, "memory not reused")
+
+	// A request for a different size frees idle memory to
+	// make room once there is enough.
+	go func() {
+		mem, err := mb.get(context.Background(), 64)
+		assert.NoError(t, err)
+		got <- mem
+	}()
+
+	mb.put(b)
+	mb.put(a)
+
+	mem := <-got
+	assert.Equal(t, uint32(64), mem.Len())
+	assert.Equal(t, uint64(64), mb.allocated, "allocated")
+	assert.Empty(t, mb.idle, "idle")
+
+	assert.True(t, mb.fits(64), "fits")
+	assert.False(t, mb.fits(65), "fits")
+}
+
+func TestMemoryBudgetTracing(t *testing.T) {
+	t.Parallel()
+
+	sr := tracetest.NewSpanRecorder()
+
+	c, _, stop := testingClient(
+		WithMemoryBudget(64*1024),
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))))
+	defer stop()
+
+	_, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	var admission int
+	for _, span := range sr.Ended() {
+		if span.Name() == "portunes.admission" {
+			admission++
+		}
+	}
+
+	assert.Equal(t, 1, admission, "admission spans")
+}
+
+func TestMemoryBudgetInvalid(t *testing.T) {
+	t.Parallel()
+
+	assert.PanicsWithValue(t, "portunes: invalid memory budget", func() {
+		WithMemoryBudget(0)
+	})
+}
+
+func benchmarkVerifyLatency(b *testing.B, sopt ...ServerOption) {
+	c, _, stop := testingClient(sopt...)
+	defer stop()
+
+	pepper := []byte("🔑📋")
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", pepper)
+	require.NoError(b, err)
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+	)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		var local []time.Duration
+		for pb.Next() {
+			start := time.Now()
+			if _, _, err := c.Verify(context.Background(), "password🔐🔓", pepper, hash); err != nil {
+				b.Fatal(err)
+			}
+			local = append(local, time.Since(start))
+		}
+
+		mu.Lock()
+		latencies = append(latencies, local...)
+		mu.Unlock()
+	})
+
+	b.StopTimer()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	b.ReportMetric(float64(latencies[len(latencies)*50/100].Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns")
+}
+
+func BenchmarkVerifyLatency(b *testing.B) {
+	b.Run("unbounded", func(b *testing.B) {
+		benchmarkVerifyLatency(b)
+	})
+	b.Run("budget", func(b *testing.B) {
+		benchmarkVerifyLatency(b, WithMemoryBudget(4*64*1024))
+	})
+}
@@ -3,6 +3,8 @@ package portunes
 import (
 	"context"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 	pb "go.tmthrgd.dev/portunes/internal/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/encoding"
@@ -13,14 +15,26 @@ import (
 type Client struct {
 	cc *grpc.ClientConn
 	pc pb.HasherClient
+
+	tracer trace.Tracer
+
+	fallback *fallback
 }
 
 // NewClient creates a Client from a given grpc.ClientConn.
-func NewClient(cc *grpc.ClientConn) *Client {
-	return &Client{
+func NewClient(cc *grpc.ClientConn, opts ...ClientOption) *Client {
+	c := &Client{
 		cc: cc,
 		pc: pb.NewHasherClient(cc),
+
+		tracer: otel.Tracer(instrumentationName),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
 // Close calls Close on the underlying grpc.ClientConn.
@@ -36,15 +50,32 @@ func (c *Client) Close() error {
 // the given hash will have a random salt prepended by the
 // server.
 //
+// If the server enforces a Policy that the password
+// violates, a *PolicyError will be returned.
+//
+// If WithLocalHashFallback was used, the hash will be
+// derived locally if the server is unavailable.
+//
 // opts can be used to provide grpc.CallOption's to the
 // underlying connection.
 func (c *Client) Hash(ctx context.Context, password string, pepper []byte, opts ...grpc.CallOption) ([]byte, error) {
+	ctx, span := c.startSpan(ctx, "portunes.Hasher/Hash")
+	defer span.End()
+
 	resp, err := c.pc.Hash(ctx, &pb.HashRequest{
-		Password: password,
+		Password: []byte(password),
 		Pepper:   pepper,
 	}, disableCompression(opts)...)
+	if err != nil && c.shouldFallback(ctx, span, "Hash", err) {
+		hash, err := c.hashLocal(ctx, password, pepper)
+		if err != nil {
+			return nil, spanError(span, clientError(err))
+		}
+
+		return hash, nil
+	}
 	if err != nil {
-		return nil, err
+		return nil, spanError(span, clientError(err))
 	}
 
 	return resp.Hash, nil
@@ -57,16 +88,41 @@ func (c *Client) Hash(ctx context.Context, password string, pepper []byte, opts
 // pepper should be as provided to the previous call to
 // Hash.
 //
+// If hash uses a version of the hash format that the
+// server doesn't understand, such as one from a newer
+// server, an error with the codes.Unimplemented status code
+// that matches ErrUnsupportedVersion will be returned. A
+// malformed hash returns an error that matches
+// ErrHashMalformed. See Error for the other errors that may
+// be returned.
+//
+// If WithLocalFallback was used, the password will be
+// verified locally if the server is unavailable, unless
+// the hash was encrypted with WithEnvelopeKeys.
+//
 // opts can be used to provide grpc.CallOption's to the
 // underlying connection.
 func (c *Client) Verify(ctx context.Context, password string, pepper, hash []byte, opts ...grpc.CallOption) (valid, rehash bool, err error) {
+	ctx, span := c.startSpan(ctx, "portunes.Hasher/Verify")
+	defer span.End()
+
 	resp, err := c.pc.Verify(ctx, &pb.VerifyRequest{
-		Password: password,
+		Password: []byte(password),
 		Pepper:   pepper,
 		Hash:     hash,
 	}, disableCompression(opts)...)
+	// Encrypted hashes can't be verified locally as the
+	// keys are only held by the server.
+	if err != nil && !isEnvelope(hash) && c.shouldFallback(ctx, span, "Verify", err) {
+		valid, err := c.verifyLocal(ctx, password, pepper, hash)
+		if err != nil {
+			return false, false, spanError(span, clientError(err))
+		}
+
+		return valid, false, nil
+	}
 	if err != nil {
-		return false, false, err
+		return false, false, spanError(span, clientError(err))
 	}
 
 	// Never return true for rehash if the password was
@@ -79,6 +135,44 @@ func (c *Client) Verify(ctx context.Context, password string, pepper, hash []byt
 	return resp.Valid, resp.Rehash && resp.Valid, nil
 }
 
+// CheckPolicy evaluates password against the policy
+// configured on the server and returns any requirements it
+// does not meet along with its estimated strength in bits.
+//
+// userInputs should contain words related to the user,
+// such as their username and email address, that should
+// not be used in their password.
+//
+// opts can be used to provide grpc.CallOption's to the
+// underlying connection.
+func (c *Client) CheckPolicy(ctx context.Context, password string, userInputs []string, opts ...grpc.CallOption) (violations []PolicyViolation, entropy float64, err error) {
+	ctx, span := c.startSpan(ctx, "portunes.Hasher/CheckPolicy")
+	defer span.End()
+
+	resp, err := c.pc.CheckPolicy(ctx, &pb.CheckPolicyRequest{
+		Password:   password,
+		UserInputs: userInputs,
+	}, disableCompression(opts)...)
+	if err != nil {
+		return nil, 0, spanError(span, clientError(err))
+	}
+
+	return fromPBViolations(resp.Violations), resp.Entropy, nil
+}
+
+// deriver returns the deriver used for local fallback.
+func (c *Client) deriver() deriver {
+	return deriver{tracer: c.tracer}
+}
+
+// startSpan starts a client span and propagates it to the
+// server in the outgoing grpc metadata.
+func (c *Client) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	ctx, span := c.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient))
+	return injectTraceContext(ctx), span
+}
+
 // disableCompression does what it says on the tin. It's
 // used to ensure the underlying transport does not
 // introduce any compression side-channels. Otherwise it
@@ -88,3 +182,18 @@ func (c *Client) Verify(ctx context.Context, password string, pepper, hash []byt
 func disableCompression(opts []grpc.CallOption) []grpc.CallOption {
 	return append(opts, grpc.UseCompressor(encoding.Identity))
 }
+
+// ClientOption allows changing the behaviour of the client.
+type ClientOption func(*Client)
+
+// WithClientTracerProvider sets the OpenTelemetry
+// TracerProvider used to record spans for each call.
+//
+// By default the global TracerProvider is used. The trace
+// context is propagated to the server with the global
+// TextMapPropagator.
+func WithClientTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(c *Client) {
+		c.tracer = tp.Tracer(instrumentationName)
+	}
+}
@@ -19,6 +19,10 @@ import (
 )
 
 func testingClient(sopt ...ServerOption) (c *Client, s *Server, stop func()) {
+	return testingClientWithOptions(nil, sopt...)
+}
+
+func testingClientWithOptions(copt []ClientOption, sopt ...ServerOption) (c *Client, s *Server, stop func()) {
 	ln := memlistener.NewMemoryListener()
 
 	srv := grpc.NewServer()
@@ -45,7 +49,7 @@ func testingClient(sopt ...ServerOption) (c *Client, s *Server, stop func()) {
 		panic(err)
 	}
 
-	return NewClient(cc), s, func() {
+	return NewClient(cc, copt...), s, func() {
 		cc.Close()
 		srv.Stop()
 		ln.Close()
@@ -233,9 +237,13 @@ var testVectors = []struct {
 	password, pepper, hash string
 	valid, rehash          bool
 }{
-	{"password🔐🔓", "🔑📋", "020001a040fa69802700907ba1bf2887cb5be9aa9850365d0d2e0a973ac5da63153c7b", true, false},
-	{"password🔐🔓", "🔑📋", "0202085587e939e96775433bd639e73d2c1cb298f55073d34d19d6375f888702402aa4", true, false},
+	// paramsV0 hashes are always marked for rehashing as
+	// they append the pepper to the salt.
+	{"password🔐🔓", "🔑📋", "020001a040fa69802700907ba1bf2887cb5be9aa9850365d0d2e0a973ac5da63153c7b", true, true},
+	{"password🔐🔓", "🔑📋", "0202085587e939e96775433bd639e73d2c1cb298f55073d34d19d6375f888702402aa4", true, true},
 	{"password🔐🔓", "🔑📋", "0200808080800895cebbae3206cf7b9087862110a2cf66618df34c4a88dfa9da279e0d3c6ee660", true, true},
+	{"password🔐🔓", "🔑📋", "17000100e94aadd1c72384c5ac8a91071d67793256dfdbc492017f313fb27b89db709c64", true, false},
+	{"password🔐🔓", "📋🔑", "17000100e94aadd1c72384c5ac8a91071d67793256dfdbc492017f313fb27b89db709c64", false, false},
 }
 
 func TestVectors(t *testing.T) {
@@ -297,6 +305,24 @@ func TestRehash(t *testing.T) {
 	}
 }
 
+func TestRehashNil(t *testing.T) {
+	t.Parallel()
+
+	c, _, stop := testingClient(WithRehashFunc(nil))
+	defer stop()
+
+	// A wrapped legacy hash would otherwise always be
+	// rehashed.
+	hash, err := c.WrapLegacy(context.Background(), []byte("$apr1$8sFt66rZ$/2KB/ChEot7Ge/1n068od/"), []byte("🔑📋"))
+	require.NoError(t, err)
+
+	valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.False(t, rehash, "rehash")
+}
+
 func TestDefaultRehash(t *testing.T) {
 	t.Parallel()
 
@@ -379,6 +405,7 @@ func TestDOSProtection(t *testing.T) {
 			require.Error(t, err)
 
 			assert.Equal(t, codes.ResourceExhausted, status.Code(err), "invalid gRPC status code")
+			assert.ErrorIs(t, err, ErrCostTooHigh)
 		}
 	}
 }
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func benchMain(args []string) {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	cf := addClientFlags(flags)
+	method := flags.String("method", "verify", "the method to call (hash or verify)")
+	concurrency := flags.Int("concurrency", runtime.GOMAXPROCS(0), "the number of concurrent requests")
+	requests := flags.Int64("requests", 0, "the number of requests to make, or 0 to run for -duration")
+	duration := flags.Duration("duration", 10*time.Second, "how long to run for, if -requests is 0 or -duration is given")
+	password := flags.String("password", "password", "the password to hash or verify")
+	pepperFile := addPepperFlag(flags)
+	flags.Parse(args)
+
+	if (*method != "hash" && *method != "verify") ||
+		*concurrency < 1 || *requests < 0 || *duration <= 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	pepper, err := readPepper(*pepperFile)
+	if err != nil {
+		log.Fatalf("failed to read pepper: %v", err)
+	}
+
+	c, err := cf.dial(context.Background())
+	if err != nil {
+		log.Fatalf("failed to connect: %v", err)
+	}
+	defer c.Close()
+
+	call := func(ctx context.Context) error {
+		_, err := c.Hash(ctx, *password, pepper)
+		return err
+	}
+
+	if *method == "verify" {
+		hash, err := c.Hash(context.Background(), *password, pepper)
+		if err != nil {
+			log.Fatalf("failed to create hash to verify: %v", err)
+		}
+
+		call = func(ctx context.Context) error {
+			valid, _, err := c.Verify(ctx, *password, pepper, hash)
+			if err == nil && !valid {
+				err = fmt.Errorf("password unexpectedly invalid")
+			}
+
+			return err
+		}
+	}
+
+	// -requests runs until that many requests have been made
+	// unless -duration was also given.
+	durationSet := false
+	flags.Visit(func(f *flag.Flag) {
+		durationSet = durationSet || f.Name == "duration"
+	})
+
+	ctx := context.Background()
+	if *requests == 0 || durationSet {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	r := runBench(ctx, call, *concurrency, *requests)
+	r.print(os.Stdout)
+}
+
+type benchResult struct {
+	elapsed   time.Duration
+	latencies []time.Duration
+	errors    int
+	firstErr  error
+}
+
+// runBench calls fn from concurrency goroutines until ctx is
+// done or, if requests is non-zero, requests calls have been
+// started.
+func runBench(ctx context.Context, fn func(context.Context) error, concurrency int, requests int64) *benchResult {
+	var (
+		started int64
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		r       benchResult
+	)
+
+	start := time.Now()
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var latencies []time.Duration
+			var errors int
+			var firstErr error
+
+			for ctx.Err() == nil {
+				if requests != 0 && atomic.AddInt64(&started, 1) > requests {
+					break
+				}
+
+				t := time.Now()
+				err := fn(ctx)
+				if ctx.Err() != nil {
+					// The call was likely cut short by the
+					// end of the run, so don't count it.
+					break
+				}
+
+				if err != nil {
+					errors++
+					if firstErr == nil {
+						firstErr = err
+					}
+
+					continue
+				}
+
+				latencies = append(latencies, time.Since(t))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			r.latencies = append(r.latencies, latencies...)
+			r.errors += errors
+			if r.firstErr == nil {
+				r.firstErr = firstErr
+			}
+		}()
+	}
+
+	wg.Wait()
+	r.elapsed = time.Since(start)
+
+	sort.Slice(r.latencies, func(i, j int) bool {
+		return r.latencies[i] < r.latencies[j]
+	})
+
+	return &r
+}
+
+// percentile returns the latency that p percent of
+// successful requests completed within.
+func (r *benchResult) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+
+	i := int(p / 100 * float64(len(r.latencies)))
+	if i >= len(r.latencies) {
+		i = len(r.latencies) - 1
+	}
+
+	return r.latencies[i]
+}
+
+func (r *benchResult) print(w io.Writer) {
+	n := len(r.latencies)
+
+	fmt.Fprintf(w, "requests:   %d (%d errors)\n", n+r.errors, r.errors)
+	fmt.Fprintf(w, "elapsed:    %s\n", r.elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "throughput: %.2f req/s\n", float64(n)/r.elapsed.Seconds())
+
+	if n != 0 {
+		fmt.Fprintf(w, "latency:    p50 %s, p90 %s, p99 %s, max %s\n",
+			r.percentile(50).Round(time.Microsecond),
+			r.percentile(90).Round(time.Microsecond),
+			r.percentile(99).Round(time.Microsecond),
+			r.latencies[n-1].Round(time.Microsecond))
+	}
+
+	if r.firstErr != nil {
+		fmt.Fprintf(w, "first error: %v\n", r.firstErr)
+	}
+}
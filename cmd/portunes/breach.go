@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"go.tmthrgd.dev/portunes/breach"
+)
+
+func buildBreachFilterMain(args []string) {
+	flags := flag.NewFlagSet("build-breach-filter", flag.ExitOnError)
+	in := flags.String("in", "", "the file of SHA-1 hashes to read, one per line, as in the Pwned Passwords list")
+	out := flags.String("out", "breach.filter", "the file to write the filter to")
+	fpRate := flags.Float64("fp-rate", 0.001, "the false positive rate of the filter")
+	flags.Parse(args)
+
+	if *in == "" || *fpRate <= 0 || *fpRate >= 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	file, err := os.Open(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	// The filter has to be sized up front, so make a first
+	// pass over the file to count the hashes.
+	n, err := countLines(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		log.Fatal(err)
+	}
+
+	f := breach.New(n, *fpRate)
+	if err := f.AddHashes(file); err != nil {
+		log.Fatal(err)
+	}
+
+	outFile, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := f.WriteTo(outFile); err != nil {
+		outFile.Close()
+		log.Fatal(err)
+	}
+
+	if err := outFile.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("wrote filter of %d hashes to %s", n, *out)
+}
+
+func countLines(r io.Reader) (uint64, error) {
+	var n uint64
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if len(s.Bytes()) != 0 {
+			n++
+		}
+	}
+
+	return n, s.Err()
+}
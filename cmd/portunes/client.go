@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"go.tmthrgd.dev/portunes"
+	"golang.org/x/term"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// clientFlags holds the flags shared by the subcommands
+// that connect to a running server.
+type clientFlags struct {
+	target     *string
+	tls        *bool
+	caCert     *string
+	cert, key  *string
+	serverName *string
+	insecure   *bool
+}
+
+func addClientFlags(flags *flag.FlagSet) *clientFlags {
+	return &clientFlags{
+		target:     flags.String("target", "localhost:8080", "the address of the server"),
+		tls:        flags.Bool("tls", false, "connect to the server with TLS"),
+		caCert:     flags.String("tls-ca-cert", "", "a PEM file of CA certificates used to verify the server, instead of the system roots"),
+		cert:       flags.String("tls-cert", "", "a PEM client certificate file to present to the server"),
+		key:        flags.String("tls-key", "", "the PEM private key file for -tls-cert"),
+		serverName: flags.String("tls-server-name", "", "the name used to verify the server's certificate, instead of the host in -target"),
+		insecure:   flags.Bool("tls-insecure-skip-verify", false, "don't verify the server's certificate"),
+	}
+}
+
+// tlsConfig returns the TLS configuration from the flags,
+// or nil if TLS is disabled.
+func (cf *clientFlags) tlsConfig() (*tls.Config, error) {
+	if !*cf.tls {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		ServerName:         *cf.serverName,
+		InsecureSkipVerify: *cf.insecure,
+	}
+
+	if *cf.caCert != "" {
+		pem, err := ioutil.ReadFile(*cf.caCert)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", *cf.caCert)
+		}
+	}
+
+	if (*cf.cert == "") != (*cf.key == "") {
+		return nil, errors.New("-tls-cert and -tls-key must be used together")
+	}
+
+	if *cf.cert != "" {
+		cert, err := tls.LoadX509KeyPair(*cf.cert, *cf.key)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// dial connects to the server given by the flags.
+func (cf *clientFlags) dial(ctx context.Context) (*portunes.Client, error) {
+	config, err := cf.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	creds := grpc.WithInsecure()
+	if config != nil {
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(config))
+	}
+
+	cc, err := grpc.DialContext(ctx, *cf.target, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	return portunes.NewClient(cc), nil
+}
+
+// readPassword reads a password from the terminal without
+// echoing it, or otherwise reads a single line from stdin.
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Password: ")
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && (err != io.EOF || password == "") {
+		return "", err
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(password, "\n"), "\r"), nil
+}
+
+// pepperEnv is the environment variable the base64 encoded
+// pepper is read from if -pepper-file isn't given. The
+// pepper is never taken as a flag, where it would be
+// visible to other users in ps and saved in shell history.
+const pepperEnv = "PORTUNES_PEPPER"
+
+func addPepperFlag(flags *flag.FlagSet) *string {
+	return flags.String("pepper-file", "", "a file containing the base64 encoded pepper, instead of the "+pepperEnv+" environment variable")
+}
+
+// readPepper returns the pepper from file, if it's not
+// empty, or otherwise from the environment. No pepper is
+// used if neither is set.
+func readPepper(file string) ([]byte, error) {
+	pepper := os.Getenv(pepperEnv)
+	if file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		pepper = string(b)
+	}
+
+	pepper = strings.TrimSpace(pepper)
+	if pepper == "" {
+		return nil, nil
+	}
+
+	return base64.StdEncoding.DecodeString(pepper)
+}
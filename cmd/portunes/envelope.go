@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.tmthrgd.dev/portunes"
+)
+
+var envelopeAlgorithms = map[string]portunes.EnvelopeAlgorithm{
+	"xchacha20poly1305": portunes.EnvelopeXChaCha20Poly1305,
+	"aesgcm":            portunes.EnvelopeAESGCM,
+}
+
+// loadEnvelopeKeys reads envelope keys from the named file.
+// Each non-blank line that doesn't start with # holds a key
+// as "<id> <algorithm> <base64 key>".
+func loadEnvelopeKeys(name string) ([]portunes.EnvelopeKey, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var keys []portunes.EnvelopeKey
+
+	s := bufio.NewScanner(file)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected <id> <algorithm> <key>", name, line)
+		}
+
+		id, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid key ID: %v", name, line, err)
+		}
+
+		alg, ok := envelopeAlgorithms[fields[1]]
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unknown algorithm %q", name, line, fields[1])
+		}
+
+		key, err := base64.StdEncoding.DecodeString(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid key: %v", name, line, err)
+		}
+
+		keys = append(keys, portunes.EnvelopeKey{
+			ID:        uint32(id),
+			Algorithm: alg,
+			Key:       key,
+		})
+	}
+
+	return keys, s.Err()
+}
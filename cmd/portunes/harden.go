@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// hardenFlags holds the flags controlling how the serve
+// command hardens the process. As the server holds
+// passwords in memory, anything that can read its memory,
+// such as a core dump or debugger, can recover them.
+type hardenFlags struct {
+	noCoreDumps *bool
+	nonDumpable *bool
+	noNewPrivs  *bool
+	user        *string
+}
+
+func addHardenFlags(flags *flag.FlagSet) *hardenFlags {
+	return &hardenFlags{
+		noCoreDumps: flags.Bool("disable-core-dumps", true, "set RLIMIT_CORE to zero so that no core dump is written if the server crashes"),
+		nonDumpable: flags.Bool("non-dumpable", false, "mark the process as not dumpable, which also prevents other processes of the same user attaching to it (Linux only)"),
+		noNewPrivs:  flags.Bool("no-new-privs", false, "prevent the process and its children gaining privileges through exec (Linux only)"),
+		user:        flags.String("user", "", "the user to switch to after binding the listener, with their primary group"),
+	}
+}
+
+// harden applies the hardening that must be done before
+// any secrets are loaded into memory.
+func (hf *hardenFlags) harden() error {
+	if *hf.noCoreDumps {
+		if err := disableCoreDumps(); err != nil {
+			return fmt.Errorf("failed to disable core dumps: %w", err)
+		}
+	}
+
+	if *hf.nonDumpable {
+		if err := setNonDumpable(); err != nil {
+			return fmt.Errorf("failed to mark process as not dumpable: %w", err)
+		}
+	}
+
+	if *hf.noNewPrivs {
+		if err := setNoNewPrivs(); err != nil {
+			return fmt.Errorf("failed to set no_new_privs: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// dropPrivileges switches to the user given by -user, if
+// any. It must be called after the listener is bound.
+func (hf *hardenFlags) dropPrivileges() error {
+	if *hf.user == "" {
+		return nil
+	}
+
+	if err := switchUser(*hf.user); err != nil {
+		return fmt.Errorf("failed to switch to user %q: %w", *hf.user, err)
+	}
+
+	return nil
+}
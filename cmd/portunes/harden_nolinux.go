@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+// setNonDumpable does nothing as PR_SET_DUMPABLE is
+// specific to Linux. Disabling core dumps covers the most
+// important case.
+func setNonDumpable() error {
+	return nil
+}
+
+// setNoNewPrivs does nothing as PR_SET_NO_NEW_PRIVS is
+// specific to Linux.
+func setNoNewPrivs() error {
+	return nil
+}
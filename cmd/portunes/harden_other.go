@@ -0,0 +1,16 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package main
+
+import "errors"
+
+// disableCoreDumps does nothing as there's no RLIMIT_CORE
+// on this platform.
+func disableCoreDumps() error {
+	return nil
+}
+
+func switchUser(name string) error {
+	return errors.New("switching user is not supported on this platform")
+}
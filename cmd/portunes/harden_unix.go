@@ -0,0 +1,64 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package main
+
+import (
+	"errors"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+func disableCoreDumps() error {
+	return syscall.Setrlimit(syscall.RLIMIT_CORE, &syscall.Rlimit{})
+}
+
+// switchUser changes the user and group IDs of the process
+// to those of name, and drops any supplementary groups.
+//
+// This relies on syscall.Setuid applying to every thread,
+// which it does on Linux as of Go 1.16, the minimum version
+// in go.mod.
+func switchUser(name string) error {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return err
+	}
+
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return err
+	}
+
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return err
+	}
+
+	// The group must be changed first as it requires
+	// privileges that are lost once the user is.
+	if err := syscall.Setgroups([]int{gid}); err != nil {
+		return err
+	}
+
+	if err := syscall.Setgid(gid); err != nil {
+		return err
+	}
+
+	if err := syscall.Setuid(uid); err != nil {
+		return err
+	}
+
+	if syscall.Getuid() != uid || syscall.Geteuid() != uid ||
+		syscall.Getgid() != gid || syscall.Getegid() != gid {
+		return errors.New("user or group ID was not changed")
+	}
+
+	// Make sure the privileges can't be regained.
+	if uid != 0 && syscall.Setuid(0) == nil {
+		return errors.New("privileges can be regained")
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+func hashMain(args []string) {
+	flags := flag.NewFlagSet("hash", flag.ExitOnError)
+	cf := addClientFlags(flags)
+	pepperFile := addPepperFlag(flags)
+	timeout := flags.Duration("timeout", 30*time.Second, "the time to wait for the server")
+	flags.Parse(args)
+
+	pepper, err := readPepper(*pepperFile)
+	if err != nil {
+		log.Fatalf("failed to read pepper: %v", err)
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		log.Fatalf("failed to read password: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	c, err := cf.dial(ctx)
+	if err != nil {
+		log.Fatalf("failed to connect: %v", err)
+	}
+	defer c.Close()
+
+	hash, err := c.Hash(ctx, password, pepper)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(base64.StdEncoding.EncodeToString(hash))
+}
+
+// These are the exit statuses of the verify command when
+// the password isn't valid. flag also exits with
+// verifyUsage for invalid flags.
+const (
+	verifyInvalid = 1
+	verifyUsage   = 2
+	verifyFailed  = 3
+)
+
+// verifyMain exits with status 0 if the password is valid,
+// 1 if it is invalid, 2 for invalid flags and 3 if it
+// couldn't be verified. It prints whether the hash should
+// be replaced with a new one.
+func verifyMain(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	cf := addClientFlags(flags)
+	hashFlag := flags.String("hash", "", "the base64 encoded hash, from hash, to verify against")
+	pepperFile := addPepperFlag(flags)
+	timeout := flags.Duration("timeout", 30*time.Second, "the time to wait for the server")
+	flags.Parse(args)
+
+	hash, err := base64.StdEncoding.DecodeString(*hashFlag)
+	if *hashFlag == "" || err != nil {
+		flags.Usage()
+		os.Exit(verifyUsage)
+	}
+
+	// log.Fatal would exit with the same status as an
+	// invalid password.
+	fatalf := func(format string, v ...interface{}) {
+		log.Printf(format, v...)
+		os.Exit(verifyFailed)
+	}
+
+	pepper, err := readPepper(*pepperFile)
+	if err != nil {
+		fatalf("failed to read pepper: %v", err)
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		fatalf("failed to read password: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	c, err := cf.dial(ctx)
+	if err != nil {
+		fatalf("failed to connect: %v", err)
+	}
+	defer c.Close()
+
+	valid, rehash, err := c.Verify(ctx, password, pepper, hash)
+	if err != nil {
+		c.Close()
+		fatalf("%v", err)
+	}
+
+	if !valid {
+		fmt.Println("invalid")
+		c.Close()
+		os.Exit(verifyInvalid)
+	}
+
+	if rehash {
+		fmt.Println("valid, rehash")
+	} else {
+		fmt.Println("valid")
+	}
+}
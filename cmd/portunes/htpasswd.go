@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"go.tmthrgd.dev/portunes/htpasswd"
+)
+
+func htpasswdMain(args []string) {
+	flags := flag.NewFlagSet("htpasswd", flag.ExitOnError)
+	cf := addClientFlags(flags)
+	file := flags.String("file", "", "the htpasswd file to modify")
+	create := flags.Bool("create", false, "create the file if it doesn't exist")
+	del := flags.Bool("delete", false, "delete the user instead of setting their password")
+	pepperFile := addPepperFlag(flags)
+	timeout := flags.Duration("timeout", 30*time.Second, "the time to wait for the server")
+	flags.Parse(args)
+
+	if *file == "" || flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	user := flags.Arg(0)
+
+	f, err := htpasswd.Load(*file)
+	switch {
+	case os.IsNotExist(err) && *create && !*del:
+		f = htpasswd.New()
+	case err != nil:
+		log.Fatal(err)
+	}
+
+	if *del {
+		if !f.Delete(user) {
+			log.Fatalf("user %q not found in %s", user, *file)
+		}
+
+		if err := f.Save(*file); err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("deleted user %q", user)
+		return
+	}
+
+	pepper, err := readPepper(*pepperFile)
+	if err != nil {
+		log.Fatalf("failed to read pepper: %v", err)
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		log.Fatalf("failed to read password: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	c, err := cf.dial(ctx)
+	if err != nil {
+		log.Fatalf("failed to connect: %v", err)
+	}
+	defer c.Close()
+
+	hash, err := c.Hash(ctx, password, pepper)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, existed := f.Get(user)
+	if err := f.Set(user, htpasswd.EncodeHash(hash)); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := f.Save(*file); err != nil {
+		log.Fatal(err)
+	}
+
+	if existed {
+		log.Printf("updated password for user %q", user)
+	} else {
+		log.Printf("added user %q", user)
+	}
+}
@@ -1,36 +1,47 @@
 package main
 
 import (
-	"flag"
-	"log"
-	"net"
+	"fmt"
 	"os"
-	"runtime"
-
-	"go.tmthrgd.dev/portunes"
-	"google.golang.org/grpc"
+	"sort"
+	"strings"
 )
 
+// commands holds the subcommands that can be given as the
+// first argument. Running without a subcommand starts the
+// server.
+var commands = map[string]func(args []string){
+	"serve":               serveMain,
+	"build-breach-filter": buildBreachFilterMain,
+	"hash":                hashMain,
+	"verify":              verifyMain,
+	"bench":               benchMain,
+	"htpasswd":            htpasswdMain,
+	"migrate":             migrateMain,
+}
+
 func main() {
-	addr := flag.String("addr", ":8080", "the address to listen on")
-	time := flag.Uint("time", 1, "the number of argon2 iterations")
-	memory := flag.Uint("memory", 64*1024, "the argon2 memory size")
-	threads := flag.Uint("threads", uint(1+runtime.GOMAXPROCS(0))/2, "the degree of parallelism for argon2")
-	flag.Parse()
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		cmd, ok := commands[os.Args[1]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown command %q, expected one of: %s\n",
+				os.Args[1], strings.Join(commandNames(), ", "))
+			os.Exit(2)
+		}
 
-	if uint(uint32(*time)) != *time ||
-		uint(uint32(*memory)) != *memory ||
-		uint(uint8(*threads)) != *threads {
-		flag.Usage()
-		os.Exit(1)
+		cmd(os.Args[2:])
+		return
 	}
 
-	ln, err := net.Listen("tcp", *addr)
-	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+	serveMain(os.Args[1:])
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
 
-	gs := grpc.NewServer()
-	portunes.NewServer(uint32(*time), uint32(*memory), uint8(*threads)).Attach(gs)
-	log.Fatal(gs.Serve(ln))
+	sort.Strings(names)
+	return names
 }
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.tmthrgd.dev/portunes"
+	"go.tmthrgd.dev/portunes/htpasswd"
+	"go.tmthrgd.dev/portunes/internal/md5crypt"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// migrateMain reads stored hashes from a CSV or JSONL file
+// and reports the distribution of their parameters. With
+// -wrap, it also wraps legacy hashes in onion hashes and
+// writes the records out with the new hashes.
+//
+// CSV files have a user and hash column, which are the
+// first two columns unless a header row names them. JSONL
+// files have one object per line with "user" and "hash"
+// string fields. Any other columns or fields are kept.
+//
+// Hashes may be htpasswd entries, from the htpasswd
+// command, or base64 encoded hashes, from the hash command.
+// Legacy bcrypt and MD5-crypt hashes are wrapped and written
+// as htpasswd entries unless -base64 is given.
+func migrateMain(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	cf := addClientFlags(flags)
+	in := flags.String("in", "-", "the CSV or JSONL file to read hashes from, - for stdin")
+	out := flags.String("out", "-", "the file to write the records to with -wrap, - for stdout")
+	format := flags.String("format", "", "the file format, csv or jsonl, by default taken from the -in extension")
+	wrap := flags.Bool("wrap", false, "wrap legacy hashes in onion hashes and write out the records")
+	b64 := flags.Bool("base64", false, "write wrapped hashes base64 encoded instead of as htpasswd entries")
+	pepperFile := addPepperFlag(flags)
+	timeout := flags.Duration("timeout", 30*time.Second, "the time to wait for the server for each hash")
+	flags.Parse(args)
+
+	if *format == "" {
+		*format = "csv"
+		switch filepath.Ext(*in) {
+		case ".jsonl", ".ndjson":
+			*format = "jsonl"
+		}
+	}
+
+	if flags.NArg() != 0 || (*format != "csv" && *format != "jsonl") ||
+		(*wrap && *in != "-" && *in == *out) {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	m := &migrator{
+		timeout: *timeout,
+		base64:  *b64,
+		counts:  make(map[string]int),
+	}
+
+	r := os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	// Without -wrap the records are only inspected.
+	w := ioutil.Discard
+	if *wrap {
+		pepper, err := readPepper(*pepperFile)
+		if err != nil {
+			log.Fatalf("failed to read pepper: %v", err)
+		}
+
+		c, err := cf.dial(context.Background())
+		if err != nil {
+			log.Fatalf("failed to connect: %v", err)
+		}
+		defer c.Close()
+
+		m.c, m.pepper = c, pepper
+
+		if *out != "-" {
+			f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+
+			w = f
+		} else {
+			w = os.Stdout
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+
+	var err error
+	if *format == "jsonl" {
+		err = m.migrateJSONL(r, bw)
+	} else {
+		err = m.migrateCSV(r, bw)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := bw.Flush(); err != nil {
+		log.Fatal(err)
+	}
+
+	m.report(os.Stderr)
+}
+
+type migrator struct {
+	c       *portunes.Client // nil unless wrapping
+	pepper  []byte
+	timeout time.Duration
+	base64  bool
+
+	counts          map[string]int
+	total           int
+	wrapped, failed int
+}
+
+// migrate records the parameters of hash and returns the
+// hash that should replace it.
+func (m *migrator) migrate(user, hash string) string {
+	desc, legacy := describeHash(hash)
+	m.counts[desc]++
+	m.total++
+
+	if !legacy || m.c == nil {
+		return hash
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	wrapped, err := m.c.WrapLegacy(ctx, []byte(hash), m.pepper)
+	if status.Code(err) == codes.InvalidArgument {
+		// The server refused this hash, likely because
+		// its cost is too high, so leave it as is.
+		log.Printf("failed to wrap hash for user %q: %v", user, err)
+		m.failed++
+		return hash
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	m.wrapped++
+
+	if m.base64 {
+		return base64.StdEncoding.EncodeToString(wrapped)
+	}
+
+	return htpasswd.EncodeHash(wrapped)
+}
+
+func (m *migrator) migrateCSV(r io.Reader, w io.Writer) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cw := csv.NewWriter(w)
+
+	userCol, hashCol := 0, 1
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if line == 1 {
+			if u, h := indexOf(record, "user"), indexOf(record, "hash"); h >= 0 {
+				userCol, hashCol = u, h
+				if err := cw.Write(record); err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		if hashCol >= len(record) || userCol >= len(record) {
+			return fmt.Errorf("line %d: missing user or hash column", line)
+		}
+
+		var user string
+		if userCol >= 0 {
+			user = record[userCol]
+		}
+
+		record[hashCol] = m.migrate(user, record[hashCol])
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (m *migrator) migrateJSONL(r io.Reader, w io.Writer) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+
+	for line := 1; s.Scan(); line++ {
+		b := s.Bytes()
+		if len(strings.TrimSpace(string(b))) == 0 {
+			continue
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(b, &obj); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var user, hash string
+		if err := json.Unmarshal(obj["hash"], &hash); err != nil || hash == "" {
+			return fmt.Errorf("line %d: missing hash field", line)
+		}
+		if u, ok := obj["user"]; ok {
+			if err := json.Unmarshal(u, &user); err != nil {
+				return fmt.Errorf("line %d: invalid user field: %w", line, err)
+			}
+		}
+
+		// Only re-encode the object if the hash changed so
+		// that the order of fields is kept otherwise.
+		if newHash := m.migrate(user, hash); newHash != hash {
+			obj["hash"], _ = json.Marshal(newHash)
+
+			var err error
+			if b, err = json.Marshal(obj); err != nil {
+				return err
+			}
+		}
+
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+
+	return s.Err()
+}
+
+// report writes the distribution of hash parameters, most
+// common first, and the number of hashes wrapped.
+func (m *migrator) report(w io.Writer) {
+	descs := make([]string, 0, len(m.counts))
+	for desc := range m.counts {
+		descs = append(descs, desc)
+	}
+
+	sort.Slice(descs, func(i, j int) bool {
+		ci, cj := m.counts[descs[i]], m.counts[descs[j]]
+		if ci != cj {
+			return ci > cj
+		}
+
+		return descs[i] < descs[j]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "count\tpercent\t")
+	for _, desc := range descs {
+		count := m.counts[desc]
+		fmt.Fprintf(tw, "%d\t%.1f%%\t  %s\n", count, 100*float64(count)/float64(m.total), desc)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "%d hashes", m.total)
+	if m.c != nil {
+		fmt.Fprintf(w, ", %d wrapped, %d failed to wrap", m.wrapped, m.failed)
+	}
+	fmt.Fprintln(w)
+}
+
+// describeHash returns a description of the parameters of
+// hash and whether it's a legacy hash that can be wrapped.
+func describeHash(hash string) (desc string, legacy bool) {
+	switch {
+	case strings.HasPrefix(hash, "$portunes$"):
+		h, err := htpasswd.DecodeHash(hash)
+		if err != nil {
+			return "invalid portunes hash", false
+		}
+
+		return describePortunesHash(h), false
+	case strings.HasPrefix(hash, "$2a$"),
+		strings.HasPrefix(hash, "$2b$"),
+		strings.HasPrefix(hash, "$2y$"):
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return "invalid bcrypt hash", false
+		}
+
+		return fmt.Sprintf("bcrypt cost=%d", cost), true
+	case strings.HasPrefix(hash, md5crypt.MagicMD5),
+		strings.HasPrefix(hash, md5crypt.MagicAPR1):
+		if _, _, err := md5crypt.Split([]byte(hash)); err != nil {
+			return "invalid md5crypt hash", false
+		}
+
+		return "md5crypt " + hash[:strings.IndexByte(hash[1:], '$')+2], true
+	}
+
+	// Many strings are valid base64, so only treat it as a
+	// portunes hash if it at least looks like one.
+	if h, err := base64.StdEncoding.DecodeString(hash); err == nil {
+		if _, err := portunes.Inspect(h); status.Code(err) != codes.InvalidArgument {
+			return describePortunesHash(h), false
+		}
+	}
+
+	return "unrecognised hash", false
+}
+
+var normNames = [...]string{
+	portunes.NormalizeNone:         "none",
+	portunes.NormalizeNFC:          "NFC",
+	portunes.NormalizeNFKC:         "NFKC",
+	portunes.NormalizeOpaqueString: "OpaqueString",
+}
+
+func describePortunesHash(hash []byte) string {
+	info, err := portunes.Inspect(hash)
+	switch {
+	case status.Code(err) == codes.Unimplemented:
+		return "portunes hash with unsupported version"
+	case err != nil:
+		return "invalid portunes hash"
+	case info.Encrypted:
+		return fmt.Sprintf("portunes encrypted key=%d", info.KeyID)
+	}
+
+	desc := fmt.Sprintf("portunes v%d %s time=%d memory=%d threads=%d norm=%s salt=%d tag=%d",
+		info.Version, info.Variant, info.Time, info.Memory, info.Threads,
+		normNames[info.Normalization], info.SaltLength, info.TagLength)
+	if info.Legacy != "" {
+		desc += " wrapping " + info.Legacy
+	}
+
+	return desc
+}
+
+func indexOf(record []string, name string) int {
+	for i, field := range record {
+		if strings.EqualFold(strings.TrimSpace(field), name) {
+			return i
+		}
+	}
+
+	return -1
+}
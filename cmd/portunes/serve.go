@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"unicode/utf8"
+
+	"go.tmthrgd.dev/portunes"
+	"go.tmthrgd.dev/portunes/breach"
+	"google.golang.org/grpc"
+)
+
+var normalizations = map[string]portunes.Normalization{
+	"none":         portunes.NormalizeNone,
+	"nfc":          portunes.NormalizeNFC,
+	"nfkc":         portunes.NormalizeNFKC,
+	"opaquestring": portunes.NormalizeOpaqueString,
+}
+
+// maxRequestOverhead is the room left in the maximum
+// message size for the other fields of a request, such as
+// the hash given to Verify or the user inputs given to
+// CheckPolicy.
+const maxRequestOverhead = 64 << 10
+
+// defaultMaxRecvMsgSize is grpc's default maximum message
+// size, which bounds any field that isn't limited.
+const defaultMaxRecvMsgSize = 4 << 20
+
+func serveMain(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "the address to listen on")
+	time := flags.Uint("time", 1, "the number of argon2 iterations")
+	memory := flags.Uint("memory", 64*1024, "the argon2 memory size")
+	threads := flags.Uint("threads", uint(1+runtime.GOMAXPROCS(0))/2, "the degree of parallelism for argon2")
+	saltLen := flags.Uint("salt-length", 16, "the length of the random salt in bytes")
+	tagLen := flags.Uint("tag-length", 16, "the length of the derived tag in bytes")
+	normalize := flags.String("normalize", "none", "the password normalization to apply (none, nfc, nfkc or opaquestring)")
+	breachFilter := flags.String("breach-filter", "", "a breach filter file, from build-breach-filter, used to reject breached passwords")
+	minLength := flags.Int("min-length", 0, "the minimum password length in characters")
+	maxLength := flags.Int("max-length", 0, "the maximum password length in characters")
+	minEntropy := flags.Float64("min-entropy", 0, "the minimum estimated password strength in bits")
+	enforcePolicy := flags.Bool("enforce-policy", false, "reject passwords that violate the policy when hashing")
+	envelopeKeys := flags.String("envelope-keys", "", "a file of keys, one \"<id> <algorithm> <base64 key>\" per line, used to encrypt hashes")
+	envelopePrimary := flags.Uint("envelope-primary", 0, "the ID of the envelope key used to encrypt new hashes")
+	lockKeys := flags.Bool("lock-envelope-keys", false, "lock the envelope keys into memory so they're never swapped to disk")
+	hardenMemory := flags.Bool("harden-memory", false, "zero passwords, peppers and derived tags in memory once they're no longer needed")
+	maxPasswordBytes := flags.Int("max-password-bytes", 1024, "the maximum password length in bytes accepted for hashing or verification, 0 for no limit")
+	maxPasswordRunes := flags.Int("max-password-runes", 0, "the maximum password length in characters accepted for hashing or verification, 0 for no limit")
+	maxPepperBytes := flags.Int("max-pepper-bytes", 1024, "the maximum pepper length in bytes, 0 for no limit")
+	memoryBudget := flags.Uint64("memory-budget", 0, "the total argon2 memory size to allow across concurrent requests, which is reused between them, 0 for no limit")
+	traceExporter := flags.String("trace", "", "the OpenTelemetry trace exporter to use (stdout or otlp)")
+	otlpEndpoint := flags.String("otlp-endpoint", "localhost:4317", "the address of the OTLP collector")
+	hf := addHardenFlags(flags)
+	flags.Parse(args)
+
+	if err := hf.harden(); err != nil {
+		log.Fatal(err)
+	}
+
+	if uint(uint32(*time)) != *time ||
+		uint(uint32(*memory)) != *memory ||
+		uint(uint8(*threads)) != *threads ||
+		uint(uint32(*saltLen)) != *saltLen ||
+		uint(uint32(*tagLen)) != *tagLen ||
+		*saltLen < 8 || *tagLen < 4 ||
+		*maxPasswordBytes < 0 || *maxPasswordRunes < 0 || *maxPepperBytes < 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	norm, ok := normalizations[*normalize]
+	if !ok {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	opts := []portunes.ServerOption{
+		portunes.WithHashLengths(uint32(*saltLen), uint32(*tagLen)),
+		portunes.WithNormalization(norm),
+		portunes.WithPolicy(portunes.Policy{
+			MinLength:  *minLength,
+			MaxLength:  *maxLength,
+			MinEntropy: *minEntropy,
+			Enforce:    *enforcePolicy,
+		}),
+		portunes.WithMaxPasswordLength(*maxPasswordBytes, *maxPasswordRunes),
+		portunes.WithMaxPepperLength(*maxPepperBytes),
+	}
+
+	if *breachFilter != "" {
+		f, err := breach.Load(*breachFilter)
+		if err != nil {
+			log.Fatalf("failed to load breach filter: %v", err)
+		}
+
+		opts = append(opts, portunes.WithBreachFilter(f))
+	}
+
+	if *hardenMemory {
+		opts = append(opts, portunes.WithHardenedMemory())
+	}
+
+	if *memoryBudget != 0 {
+		opts = append(opts, portunes.WithMemoryBudget(*memoryBudget))
+	}
+
+	var keys []portunes.EnvelopeKey
+	if *envelopeKeys != "" {
+		var err error
+		keys, err = loadEnvelopeKeys(*envelopeKeys)
+		if err != nil {
+			log.Fatalf("failed to load envelope keys: %v", err)
+		}
+
+		if uint(uint32(*envelopePrimary)) != *envelopePrimary {
+			flags.Usage()
+			os.Exit(1)
+		}
+
+		opts = append(opts, portunes.WithEnvelopeKeys(uint32(*envelopePrimary), keys...))
+	}
+
+	shutdownTracing, err := setupTracing(context.Background(), *traceExporter, *otlpEndpoint)
+	if err != nil {
+		log.Fatalf("failed to setup tracing: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+
+	s := portunes.NewServer(uint32(*time), uint32(*memory), uint8(*threads), opts...)
+
+	// The server holds its own copy of the keys.
+	for _, key := range keys {
+		for i := range key.Key {
+			key.Key[i] = 0
+		}
+	}
+
+	if *lockKeys {
+		if err := s.LockEnvelopeKeys(); err != nil {
+			log.Fatalf("failed to lock envelope keys: %v", err)
+		}
+	}
+
+	// Privileges are only dropped once the listener is bound
+	// and the keys are locked, which may require them.
+	if err := hf.dropPrivileges(); err != nil {
+		log.Fatal(err)
+	}
+
+	passwordBytes := *maxPasswordBytes
+	if passwordBytes == 0 {
+		passwordBytes = utf8.UTFMax * *maxPasswordRunes
+	}
+
+	var gopts []grpc.ServerOption
+	if passwordBytes != 0 || *maxPepperBytes != 0 {
+		pepperBytes := *maxPepperBytes
+		if passwordBytes == 0 {
+			passwordBytes = defaultMaxRecvMsgSize
+		}
+		if pepperBytes == 0 {
+			pepperBytes = defaultMaxRecvMsgSize
+		}
+
+		// Reject oversized requests before they're read into
+		// memory rather than only once they reach the handler.
+		gopts = append(gopts, grpc.MaxRecvMsgSize(
+			passwordBytes+pepperBytes+maxRequestOverhead))
+	}
+
+	gs := grpc.NewServer(gopts...)
+	s.Attach(gs)
+
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		<-sigs
+
+		gs.GracefulStop()
+	}()
+
+	if err := gs.Serve(ln); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Fatalf("failed to flush traces: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package portunes
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+
+	"go.opentelemetry.io/otel/trace"
+	pb "go.tmthrgd.dev/portunes/internal/proto"
+	"google.golang.org/grpc"
+)
+
+// initDummy generates the random salt and tag that
+// VerifyDummy compares against. No password will ever
+// match them.
+func (s *Server) initDummy() {
+	buf := make([]byte, s.saltLen+s.tagLen)
+	if _, err := rand.Read(buf); err != nil {
+		panic("portunes: failed to generate dummy hash: " + err.Error())
+	}
+
+	s.dummySalt, s.dummyTag = buf[:s.saltLen:s.saltLen], buf[s.saltLen:]
+}
+
+func (s pbServer) VerifyDummy(ctx context.Context, req *pb.VerifyDummyRequest) (*pb.VerifyResponse, error) {
+	ctx, span := s.tracer.Start(extractTraceContext(ctx), "portunes.Hasher/VerifyDummy",
+		trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	if s.hardened {
+		defer wipe(req.Password)
+		defer wipe(req.Pepper)
+	}
+
+	if err := checkPassword(req.Password); err != nil {
+		return nil, spanError(span, err)
+	}
+
+	if err := s.checkLimits(req.Password, req.Pepper); err != nil {
+		return nil, spanError(span, err)
+	}
+
+	// Mirror the work done by Verify as closely as possible
+	// with a hash at the current parameters.
+	p := s.hashParams()
+
+	mem, err := s.admit(ctx, &p, s.dosProt)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer s.release(mem)
+
+	expect, err := s.deriver(mem).deriveKey(ctx, req.Password, req.Pepper, s.dummySalt, &p)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	if s.hardened {
+		defer wipe(expect)
+	}
+
+	subtle.ConstantTimeCompare(expect, s.dummyTag)
+
+	if s.rehash != nil {
+		s.rehash(ctx, p.time, p.memory, p.threads)
+	}
+
+	return &pb.VerifyResponse{
+		Valid:  false,
+		Rehash: false,
+	}, nil
+}
+
+// VerifyDummy performs the same work as Verify would for a
+// hash created with the server's current parameters, but
+// never succeeds. It should be called when a user does not
+// exist so that the time taken to reject the login does not
+// reveal whether the account exists.
+//
+// An error is only returned if the call itself failed.
+//
+// If WithLocalHashFallback was used, the work will be done
+// locally if the server is unavailable.
+//
+// opts can be used to provide grpc.CallOption's to the
+// underlying connection.
+func (c *Client) VerifyDummy(ctx context.Context, password string, pepper []byte, opts ...grpc.CallOption) error {
+	ctx, span := c.startSpan(ctx, "portunes.Hasher/VerifyDummy")
+	defer span.End()
+
+	_, err := c.pc.VerifyDummy(ctx, &pb.VerifyDummyRequest{
+		Password: []byte(password),
+		Pepper:   pepper,
+	}, disableCompression(opts)...)
+	if err != nil && c.shouldFallback(ctx, span, "VerifyDummy", err) {
+		// Hashing the password locally does the same work
+		// as verifying it against a dummy hash would.
+		if _, err := c.hashLocal(ctx, password, pepper); err != nil {
+			return spanError(span, clientError(err))
+		}
+
+		return nil
+	}
+	if err != nil {
+		return spanError(span, clientError(err))
+	}
+
+	return nil
+}
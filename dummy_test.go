@@ -0,0 +1,64 @@
+package portunes
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestVerifyDummy(t *testing.T) {
+	t.Parallel()
+
+	var memory uint32
+	c, s, stop := testingClient(
+		WithDOSProtectionFunc(func(_ context.Context, _, m uint32, _ uint8) bool {
+			atomic.StoreUint32(&memory, m)
+			return true
+		}))
+	defer stop()
+
+	require.NoError(t, c.VerifyDummy(context.Background(), "password🔐🔓", []byte("🔑📋")))
+	assert.Equal(t, uint32(64*1024), atomic.LoadUint32(&memory), "memory")
+
+	s.SetParameters(1, 32*1024, 1)
+
+	require.NoError(t, c.VerifyDummy(context.Background(), "password🔐🔓", []byte("🔑📋")))
+	assert.Equal(t, uint32(32*1024), atomic.LoadUint32(&memory), "memory")
+}
+
+func TestVerifyDummyDOSProtection(t *testing.T) {
+	t.Parallel()
+
+	c, _, stop := testingClient(
+		WithDOSProtectionFunc(func(context.Context, uint32, uint32, uint8) bool {
+			return false
+		}))
+	defer stop()
+
+	err := c.VerifyDummy(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.Error(t, err)
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "invalid gRPC status code")
+}
+
+func TestVerifyDummyFallback(t *testing.T) {
+	t.Parallel()
+
+	c := unavailableClient(WithLocalFallback(1))
+	defer c.Close()
+
+	err := c.VerifyDummy(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	assert.Equal(t, codes.Unavailable, status.Code(err), "invalid gRPC status code")
+
+	c = unavailableClient(
+		WithLocalFallback(1),
+		WithLocalHashFallback(1, 64*1024, 2))
+	defer c.Close()
+
+	assert.NoError(t, c.VerifyDummy(context.Background(), "password🔐🔓", []byte("🔑📋")))
+}
@@ -0,0 +1,179 @@
+package portunes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// EnvelopeAlgorithm is an AEAD used to encrypt hashes at
+// rest.
+type EnvelopeAlgorithm uint8
+
+const (
+	// EnvelopeXChaCha20Poly1305 uses XChaCha20-Poly1305
+	// with a 32-byte key. It is the recommended algorithm
+	// as its 24-byte nonces can be safely chosen at random.
+	EnvelopeXChaCha20Poly1305 EnvelopeAlgorithm = iota + 1
+	// EnvelopeAESGCM uses AES-GCM with a 16, 24 or 32-byte
+	// key. As its nonces are only 12-bytes, a single key
+	// should not be used to encrypt more than 2^32 hashes.
+	EnvelopeAESGCM
+)
+
+// EnvelopeKey is a key used to encrypt hashes at rest.
+type EnvelopeKey struct {
+	// ID identifies the key and is stored alongside each
+	// hash it encrypts.
+	ID uint32
+
+	Algorithm EnvelopeAlgorithm
+	Key       []byte
+}
+
+func (k *EnvelopeKey) aead() (cipher.AEAD, error) {
+	switch k.Algorithm {
+	case EnvelopeXChaCha20Poly1305:
+		return chacha20poly1305.NewX(k.Key)
+	case EnvelopeAESGCM:
+		block, err := aes.NewCipher(k.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		return cipher.NewGCM(block)
+	default:
+		panic("portunes: invalid envelope algorithm")
+	}
+}
+
+type keyring struct {
+	primary uint32
+	keys    map[uint32]*EnvelopeKey
+
+	// locked is true if the keys have been moved into
+	// locked memory, see LockEnvelopeKeys.
+	locked bool
+}
+
+func newKeyring(primary uint32, keys []EnvelopeKey) *keyring {
+	kr := &keyring{
+		primary: primary,
+		keys:    make(map[uint32]*EnvelopeKey, len(keys)),
+	}
+
+	for _, key := range keys {
+		key := key // capture range variable
+
+		if _, dup := kr.keys[key.ID]; dup {
+			panic("portunes: duplicate envelope key ID")
+		}
+
+		key.Key = append([]byte(nil), key.Key...)
+		if _, err := key.aead(); err != nil {
+			panic("portunes: invalid envelope key: " + err.Error())
+		}
+
+		kr.keys[key.ID] = &key
+	}
+
+	if _, ok := kr.keys[primary]; !ok {
+		panic("portunes: missing primary envelope key")
+	}
+
+	return kr
+}
+
+// appendEnvelopeHeader appends the envelope version marker
+// and key ID. The header is authenticated as additional data.
+func appendEnvelopeHeader(buf []byte, keyID uint32) []byte {
+	buf = appendVarint32(buf, (1<<envelopeV)-1)
+	return appendVarint32(buf, keyID)
+}
+
+// seal encrypts hash with the primary key.
+func (kr *keyring) seal(hash []byte) ([]byte, error) {
+	aead, err := kr.keys[kr.primary].aead()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, 0, 2*binary.MaxVarintLen32+aead.NonceSize()+len(hash)+aead.Overhead())
+	res = appendEnvelopeHeader(res, kr.primary)
+	hdrLen := len(res)
+
+	res = res[:hdrLen+aead.NonceSize()]
+	nonce := res[hdrLen:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(res, nonce, hash, res[:hdrLen]), nil
+}
+
+// consumeEnvelopeHeader returns the key ID from the header
+// of an envelope and the remaining nonce and ciphertext.
+func consumeEnvelopeHeader(hash []byte) (keyID uint32, rest []byte, err error) {
+	_, rest, _ = consumeVarint32(hash)
+	keyID, rest, ok := consumeVarint32(rest)
+	if !ok {
+		return 0, nil, errHashMalformed()
+	}
+
+	return keyID, rest, nil
+}
+
+// envelopeKeyID returns the ID of the key an envelope was
+// encrypted with.
+func envelopeKeyID(hash []byte) (uint32, error) {
+	keyID, _, err := consumeEnvelopeHeader(hash)
+	return keyID, err
+}
+
+// open decrypts a hash previously encrypted with seal. The
+// hash must be an envelope, see isEnvelope.
+func (kr *keyring) open(hash []byte) (inner []byte, keyID uint32, err error) {
+	keyID, rest, err := consumeEnvelopeHeader(hash)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hdr := hash[:len(hash)-len(rest)]
+
+	if kr == nil {
+		return nil, 0, status.Error(codes.FailedPrecondition, "hash is encrypted but no envelope keys are configured")
+	}
+
+	key, ok := kr.keys[keyID]
+	if !ok {
+		return nil, 0, status.Errorf(codes.FailedPrecondition, "hash is encrypted with unknown envelope key %d", keyID)
+	}
+
+	aead, err := key.aead()
+	if err != nil {
+		return nil, 0, status.Error(codes.Internal, err.Error())
+	}
+
+	if len(rest) < aead.NonceSize() {
+		return nil, 0, errHashMalformed()
+	}
+
+	nonce, ciphertext := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+	inner, err = aead.Open(nil, nonce, ciphertext, hdr)
+	if err != nil {
+		return nil, 0, errHashMalformed()
+	}
+
+	return inner, keyID, nil
+}
+
+// isEnvelope reports whether hash has been encrypted with a
+// server key.
+func isEnvelope(hash []byte) bool {
+	return hashVersion(hash) == envelopeV
+}
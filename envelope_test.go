@@ -0,0 +1,150 @@
+package portunes
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	testEnvelopeKey1 = EnvelopeKey{
+		ID:        1,
+		Algorithm: EnvelopeXChaCha20Poly1305,
+		Key:       bytes.Repeat([]byte{0x01}, 32),
+	}
+	testEnvelopeKey2 = EnvelopeKey{
+		ID:        2,
+		Algorithm: EnvelopeAESGCM,
+		Key:       bytes.Repeat([]byte{0x02}, 32),
+	}
+)
+
+func TestEnvelope(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []EnvelopeKey{testEnvelopeKey1, testEnvelopeKey2} {
+		c, _, stop := testingClient(WithEnvelopeKeys(key.ID, key))
+		defer stop()
+
+		hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+		require.NoError(t, err)
+
+		t.Logf("%d:%02x", len(hash), hash)
+
+		assert.True(t, isEnvelope(hash), "isEnvelope")
+		_, _, ok := consumeParams(hash)
+		assert.False(t, ok, "consumeParams accepted envelope")
+
+		valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+		require.NoError(t, err)
+
+		assert.True(t, valid, "valid")
+		assert.False(t, rehash, "rehash")
+
+		valid, rehash, err = c.Verify(context.Background(), "wrong🔑📋", []byte("🔑📋"), hash)
+		require.NoError(t, err)
+
+		assert.False(t, valid, "valid")
+		assert.False(t, rehash, "rehash")
+
+		tampered := append([]byte(nil), hash...)
+		tampered[len(tampered)-1] ^= 0x01
+
+		_, _, err = c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), tampered)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+	}
+}
+
+func TestEnvelopeRotation(t *testing.T) {
+	t.Parallel()
+
+	c1, _, stop1 := testingClient(WithEnvelopeKeys(1, testEnvelopeKey1))
+	defer stop1()
+
+	hash1, err := c1.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	c2, _, stop2 := testingClient(WithEnvelopeKeys(2, testEnvelopeKey1, testEnvelopeKey2))
+	defer stop2()
+
+	valid, rehash, err := c2.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash1)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.True(t, rehash, "rehash")
+
+	hash2, err := c2.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	valid, rehash, err = c2.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash2)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.False(t, rehash, "rehash")
+
+	_, _, err = c1.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash2)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "invalid gRPC status code")
+}
+
+func TestEnvelopeUnencrypted(t *testing.T) {
+	t.Parallel()
+
+	c1, _, stop1 := testingClient()
+	defer stop1()
+
+	hash, err := c1.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	c2, _, stop2 := testingClient(WithEnvelopeKeys(1, testEnvelopeKey1))
+	defer stop2()
+
+	valid, rehash, err := c2.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.True(t, rehash, "rehash")
+
+	hash, err = c2.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	_, _, err = c1.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "invalid gRPC status code")
+}
+
+func TestEnvelopeRehashNil(t *testing.T) {
+	t.Parallel()
+
+	c1, _, stop1 := testingClient()
+	defer stop1()
+
+	hash, err := c1.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	c2, _, stop2 := testingClient(WithEnvelopeKeys(1, testEnvelopeKey1), WithRehashFunc(nil))
+	defer stop2()
+
+	valid, rehash, err := c2.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.False(t, rehash, "rehash")
+}
+
+func TestEnvelopeKeysInvalid(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() { WithEnvelopeKeys(3, testEnvelopeKey1) }, "missing primary")
+	assert.Panics(t, func() { WithEnvelopeKeys(1, testEnvelopeKey1, testEnvelopeKey1) }, "duplicate ID")
+	assert.Panics(t, func() {
+		WithEnvelopeKeys(1, EnvelopeKey{
+			ID:        1,
+			Algorithm: EnvelopeXChaCha20Poly1305,
+			Key:       make([]byte, 16),
+		})
+	}, "short key")
+}
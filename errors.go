@@ -0,0 +1,182 @@
+package portunes
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	pb "go.tmthrgd.dev/portunes/internal/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain is the domain of the errdetails.ErrorInfo
+// details attached to errors from the server.
+const errorDomain = "portunes.tmthrgd.dev"
+
+// These are the reasons given in errdetails.ErrorInfo
+// details.
+const (
+	reasonHashMalformed      = "HASH_MALFORMED"
+	reasonUnsupportedVersion = "UNSUPPORTED_VERSION"
+	reasonCostTooHigh        = "COST_TOO_HIGH"
+	reasonRateLimited        = "RATE_LIMITED"
+)
+
+// These errors are matched, with errors.Is, by the *Error
+// that Client returns when a request fails for the
+// corresponding reason.
+var (
+	// ErrHashMalformed means the hash is corrupt or wasn't
+	// produced by portunes.
+	ErrHashMalformed = errors.New("portunes: malformed hash")
+
+	// ErrUnsupportedVersion means the hash uses a version of
+	// the hash format that's unknown, such as one from a
+	// newer server. The version is in the "version" key of
+	// Error.Metadata.
+	ErrUnsupportedVersion = errors.New("portunes: unsupported hash version")
+
+	// ErrCostTooHigh means the work cost of the hash was
+	// refused by the WithDOSProtectionFunc callback or
+	// exceeds the WithMemoryBudget budget. The cost is in
+	// the "time", "memory" and "threads" keys of
+	// Error.Metadata.
+	ErrCostTooHigh = errors.New("portunes: hash cost too high")
+
+	// ErrRateLimited means the request was refused by the
+	// WithRateLimitFunc callback. It may be retried after
+	// Error.RetryDelay.
+	ErrRateLimited = errors.New("portunes: rate limited")
+)
+
+var reasonErrors = map[string]error{
+	reasonHashMalformed:      ErrHashMalformed,
+	reasonUnsupportedVersion: ErrUnsupportedVersion,
+	reasonCostTooHigh:        ErrCostTooHigh,
+	reasonRateLimited:        ErrRateLimited,
+}
+
+// Error is returned from Client when a request fails with
+// an errdetails.ErrorInfo detail. errors.Is reports whether
+// it matches ErrHashMalformed, ErrUnsupportedVersion,
+// ErrCostTooHigh or ErrRateLimited.
+type Error struct {
+	// Reason is the reason given in the ErrorInfo, such as
+	// "HASH_MALFORMED".
+	Reason string
+
+	// Metadata is any additional information about the
+	// error given in the ErrorInfo.
+	Metadata map[string]string
+
+	// RetryDelay is the time to wait before retrying the
+	// request, taken from any errdetails.RetryInfo detail.
+	RetryDelay time.Duration
+
+	status *status.Status
+}
+
+func (e *Error) Error() string {
+	return "portunes: " + e.status.Message()
+}
+
+// Is reports whether target is the sentinel error for
+// e.Reason.
+func (e *Error) Is(target error) bool {
+	err, ok := reasonErrors[e.Reason]
+	return ok && err == target
+}
+
+// GRPCStatus returns the underlying grpc status so that
+// status.Code and status.FromError work as expected.
+func (e *Error) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// reasonError returns a grpc status error with an
+// errdetails.ErrorInfo detail for reason and metadata,
+// followed by any other details.
+func reasonError(code codes.Code, msg, reason string, metadata map[string]string, details ...proto.Message) error {
+	details = append([]proto.Message{&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   errorDomain,
+		Metadata: metadata,
+	}}, details...)
+
+	st, err := status.New(code, msg).WithDetails(details...)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return st.Err()
+}
+
+func errHashMalformed() error {
+	return reasonError(codes.InvalidArgument, "invalid hash", reasonHashMalformed, nil)
+}
+
+func errUnsupportedVersion(vers int) error {
+	return reasonError(codes.Unimplemented, "unsupported hash version "+strconv.Itoa(vers),
+		reasonUnsupportedVersion, map[string]string{
+			"version": strconv.Itoa(vers),
+		})
+}
+
+func errCostTooHigh(msg string, p *params) error {
+	return reasonError(codes.ResourceExhausted, msg, reasonCostTooHigh, map[string]string{
+		"time":    strconv.FormatUint(uint64(p.time), 10),
+		"memory":  strconv.FormatUint(uint64(p.memory), 10),
+		"threads": strconv.FormatUint(uint64(p.threads), 10),
+	})
+}
+
+func errRateLimited(retryDelay time.Duration) error {
+	return reasonError(codes.ResourceExhausted, "rate limited", reasonRateLimited, nil,
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(retryDelay)})
+}
+
+// clientError converts a grpc status error into a
+// *PolicyError or *Error if it carries the corresponding
+// details. Other errors are returned unchanged.
+func clientError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	var (
+		info       *errdetails.ErrorInfo
+		retryDelay time.Duration
+	)
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *pb.CheckPolicyResponse:
+			return &PolicyError{
+				Violations: fromPBViolations(detail.Violations),
+				status:     st,
+			}
+		case *errdetails.ErrorInfo:
+			if detail.Domain == errorDomain {
+				info = detail
+			}
+		case *errdetails.RetryInfo:
+			retryDelay = detail.RetryDelay.AsDuration()
+		}
+	}
+
+	if info == nil {
+		return err
+	}
+
+	return &Error{
+		Reason:     info.Reason,
+		Metadata:   info.Metadata,
+		RetryDelay: retryDelay,
+
+		status: st,
+	}
+}
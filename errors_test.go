@@ -0,0 +1,142 @@
+package portunes
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hydrogen18/memlistener"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "go.tmthrgd.dev/portunes/internal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	limited := true
+	c, _, stop := testingClient(WithRateLimitFunc(func(context.Context) time.Duration {
+		if limited {
+			return 3 * time.Second
+		}
+
+		return 0
+	}))
+	defer stop()
+
+	_, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "invalid gRPC status code")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.NotErrorIs(t, err, ErrCostTooHigh)
+
+	var perr *Error
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, "RATE_LIMITED", perr.Reason)
+	assert.Equal(t, 3*time.Second, perr.RetryDelay, "retry delay")
+
+	err = c.VerifyDummy(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	_, err = c.WrapLegacy(context.Background(), []byte("$apr1$8sFt66rZ$/2KB/ChEot7Ge/1n068od/"), []byte("🔑📋"))
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	limited = false
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	limited = true
+
+	_, _, err = c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	assert.ErrorIs(t, err, ErrRateLimited)
+}
+
+func TestCostTooHighMemoryBudget(t *testing.T) {
+	t.Parallel()
+
+	c, _, stop := testingClient(WithMemoryBudget(32 * 1024))
+	defer stop()
+
+	_, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	assert.ErrorIs(t, err, ErrCostTooHigh)
+
+	var perr *Error
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, map[string]string{
+		"time":    "1",
+		"memory":  "65536",
+		"threads": "2",
+	}, perr.Metadata)
+	assert.Zero(t, perr.RetryDelay, "retry delay")
+}
+
+func TestClientError(t *testing.T) {
+	t.Parallel()
+
+	for _, err := range []error{
+		errors.New("not a status error"),
+		status.Error(codes.InvalidArgument, "invalid hash"),
+		reasonError(codes.InvalidArgument, "other", "UNKNOWN_REASON", nil),
+	} {
+		converted := clientError(err)
+		for _, target := range []error{
+			ErrHashMalformed,
+			ErrUnsupportedVersion,
+			ErrCostTooHigh,
+			ErrRateLimited,
+		} {
+			assert.NotErrorIs(t, converted, target)
+		}
+	}
+
+	err := clientError(errHashMalformed())
+	assert.EqualError(t, err, "portunes: invalid hash")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+}
+
+// failingCheckPolicy fails every CheckPolicy call with err.
+type failingCheckPolicy struct {
+	pbServer
+	err error
+}
+
+func (s failingCheckPolicy) CheckPolicy(context.Context, *pb.CheckPolicyRequest) (*pb.CheckPolicyResponse, error) {
+	return nil, s.err
+}
+
+func TestCheckPolicyClientError(t *testing.T) {
+	t.Parallel()
+
+	ln := memlistener.NewMemoryListener()
+	defer ln.Close()
+
+	srv := grpc.NewServer()
+	pb.RegisterHasherServer(srv, failingCheckPolicy{
+		pbServer: pbServer{NewServer(1, 64*1024, 2)},
+		err:      errRateLimited(3 * time.Second),
+	})
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	cc, err := grpc.Dial("",
+		grpc.WithDialer(func(addr string, dl time.Duration) (net.Conn, error) {
+			return ln.Dial("test", addr)
+		}),
+		grpc.WithInsecure(),
+	)
+	require.NoError(t, err)
+	defer cc.Close()
+
+	_, _, err = NewClient(cc).CheckPolicy(context.Background(), "password🔐🔓", nil)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "invalid gRPC status code")
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	var perr *Error
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, 3*time.Second, perr.RetryDelay, "retry delay")
+}
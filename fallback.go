@@ -0,0 +1,215 @@
+package portunes
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// These are the default limits on the cost of hashes that
+// are verified locally, see WithLocalFallbackLimits.
+const (
+	defaultFallbackMaxTime   = 16
+	defaultFallbackMaxMemory = 1024 * 1024 // 1 GiB
+)
+
+// fallback holds the configuration for local hashing when
+// the server is unavailable.
+type fallback struct {
+	sem chan struct{}
+
+	hashParams *params
+
+	maxTime, maxMemory uint32
+
+	fn func(ctx context.Context, method string, err error)
+}
+
+func newFallback() *fallback {
+	return &fallback{
+		maxTime:   defaultFallbackMaxTime,
+		maxMemory: defaultFallbackMaxMemory,
+	}
+}
+
+// WithLocalFallback enables verifying passwords locally
+// when the server returns codes.Unavailable.
+//
+// At most maxConcurrent local argon2 computations will run
+// at once, further calls block until one finishes or their
+// context is cancelled. Locally verified passwords never
+// report that they should be rehashed.
+//
+// Local verification requires the same memory as the
+// server would use, so maxConcurrent should be kept low.
+// Hashes that cost more than the WithLocalFallbackLimits
+// limits are refused with ErrCostTooHigh.
+func WithLocalFallback(maxConcurrent int) ClientOption {
+	if maxConcurrent < 1 {
+		panic("portunes: invalid local fallback concurrency")
+	}
+
+	return func(c *Client) {
+		if c.fallback == nil {
+			c.fallback = newFallback()
+		}
+
+		c.fallback.sem = make(chan struct{}, maxConcurrent)
+	}
+}
+
+// WithLocalHashFallback additionally allows Hash and
+// VerifyDummy to fall back to hashing locally with the
+// given Argon2id cost parameters. See Server.SetParameters
+// for their meaning.
+//
+// Local hashing knows nothing of the server's
+// configuration. It doesn't check the breach filter or
+// password policy, doesn't normalize the password and
+// always uses Argon2id with 16-byte salts and tags. Hashes
+// created during an outage may therefore be of passwords
+// the server would have rejected. They're only marked for
+// rehashing if the server uses a different normalization,
+// salt or tag length, variant or cost. Use WithFallbackFunc
+// to log when this happens.
+//
+// It has no effect unless WithLocalFallback is also used.
+func WithLocalHashFallback(time, memory uint32, threads uint8) ClientOption {
+	if time < 1 || threads < 1 {
+		panic("portunes: invalid argon2 paramaters")
+	}
+
+	return func(c *Client) {
+		if c.fallback == nil {
+			c.fallback = newFallback()
+		}
+
+		c.fallback.hashParams = &params{
+			vers: paramsV3,
+
+			time:    time,
+			memory:  memory,
+			threads: threads,
+
+			saltLen: defaultSaltLen,
+			tagLen:  defaultTagLen,
+
+			variant: Argon2id,
+		}
+	}
+}
+
+// WithLocalFallbackLimits sets the maximum argon2 time and
+// memory cost of hashes that will be verified locally, so
+// that a stored hash can't make the client do arbitrarily
+// expensive work. See Server.SetParameters for their
+// meaning.
+//
+// By default, hashes with a time of up to 16 and memory of
+// up to 1 GiB are verified.
+func WithLocalFallbackLimits(maxTime, maxMemory uint32) ClientOption {
+	if maxTime < 1 {
+		panic("portunes: invalid local fallback limits")
+	}
+
+	return func(c *Client) {
+		if c.fallback == nil {
+			c.fallback = newFallback()
+		}
+
+		c.fallback.maxTime = maxTime
+		c.fallback.maxMemory = maxMemory
+	}
+}
+
+// WithFallbackFunc sets a callback that is invoked each
+// time a call falls back to local hashing. method is the
+// name of the Client method, such as "Verify", and err is
+// the error returned by the server. It is intended for
+// recording metrics.
+func WithFallbackFunc(fn func(ctx context.Context, method string, err error)) ClientOption {
+	return func(c *Client) {
+		if c.fallback == nil {
+			c.fallback = newFallback()
+		}
+
+		c.fallback.fn = fn
+	}
+}
+
+// shouldFallback reports whether the error returned from
+// the server for method permits falling back to local
+// hashing. If it does, the fallback callback is invoked.
+func (c *Client) shouldFallback(ctx context.Context, span trace.Span, method string, err error) bool {
+	f := c.fallback
+	if f == nil || f.sem == nil ||
+		(method != "Verify" && f.hashParams == nil) ||
+		status.Code(err) != codes.Unavailable {
+		return false
+	}
+
+	span.SetAttributes(attribute.Bool("portunes.fallback", true))
+
+	if f.fn != nil {
+		f.fn(ctx, method, err)
+	}
+
+	return true
+}
+
+func (f *fallback) acquire(ctx context.Context) error {
+	select {
+	case f.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+func (f *fallback) release() {
+	<-f.sem
+}
+
+func (c *Client) hashLocal(ctx context.Context, password string, pepper []byte) ([]byte, error) {
+	f := c.fallback
+
+	salt, err := newSalt(f.hashParams)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer f.release()
+
+	tag, err := c.deriver().deriveKey(ctx, []byte(password), pepper, salt, f.hashParams)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeHash(f.hashParams, salt, tag), nil
+}
+
+func (c *Client) verifyLocal(ctx context.Context, password string, pepper, hash []byte) (valid bool, err error) {
+	f := c.fallback
+
+	d, err := decodeHash(nil, hash)
+	if err != nil {
+		return false, err
+	}
+
+	if d.params.time > f.maxTime || d.params.memory > f.maxMemory {
+		return false, errCostTooHigh("cost exceeds the local fallback limits", &d.params)
+	}
+
+	if err := f.acquire(ctx); err != nil {
+		return false, err
+	}
+	defer f.release()
+
+	return d.format.verify(ctx, c.deriver(), d, []byte(password), pepper)
+}
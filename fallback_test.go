@@ -0,0 +1,147 @@
+package portunes
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func unavailableClient(copt ...ClientOption) *Client {
+	cc, err := grpc.Dial("",
+		grpc.WithDialer(func(addr string, dl time.Duration) (net.Conn, error) {
+			return nil, errors.New("portunes: server unavailable")
+		}),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	return NewClient(cc, copt...)
+}
+
+func TestFallbackDisabled(t *testing.T) {
+	t.Parallel()
+
+	c := unavailableClient()
+	defer c.Close()
+
+	_, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	assert.Equal(t, codes.Unavailable, status.Code(err), "invalid gRPC status code")
+
+	_, _, err = c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), nil)
+	assert.Equal(t, codes.Unavailable, status.Code(err), "invalid gRPC status code")
+}
+
+func TestFallbackVerify(t *testing.T) {
+	t.Parallel()
+
+	sc, _, stop := testingClient()
+	defer stop()
+
+	hash, err := sc.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	var calls uint32
+	c := unavailableClient(
+		WithLocalFallback(1),
+		WithFallbackFunc(func(ctx context.Context, method string, err error) {
+			assert.Equal(t, "Verify", method)
+			assert.Equal(t, codes.Unavailable, status.Code(err), "invalid gRPC status code")
+			atomic.AddUint32(&calls, 1)
+		}))
+	defer c.Close()
+
+	valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.False(t, rehash, "rehash")
+
+	valid, _, err = c.Verify(context.Background(), "wrong🔑📋", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.False(t, valid, "valid")
+
+	_, _, err = c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash[:len(hash)-1])
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+	assert.ErrorIs(t, err, ErrHashMalformed)
+
+	assert.Equal(t, uint32(3), atomic.LoadUint32(&calls), "fallback calls")
+
+	// Without WithLocalHashFallback, Hash must not fall back.
+	_, err = c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	assert.Equal(t, codes.Unavailable, status.Code(err), "invalid gRPC status code")
+}
+
+func TestFallbackHash(t *testing.T) {
+	t.Parallel()
+
+	c := unavailableClient(
+		WithLocalFallback(1),
+		WithLocalHashFallback(1, 64*1024, 2))
+	defer c.Close()
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	t.Logf("%d:%02x", len(hash), hash)
+
+	sc, _, stop := testingClient()
+	defer stop()
+
+	valid, rehash, err := sc.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.False(t, rehash, "rehash")
+}
+
+func TestFallbackVerifyLimits(t *testing.T) {
+	t.Parallel()
+
+	sc, s, stop := testingClient()
+	defer stop()
+
+	s.SetParameters(2, 64*1024, 2)
+
+	hash, err := sc.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		time, memory uint32
+		ok           bool
+	}{
+		{2, 64 * 1024, true},
+		{1, 64 * 1024, false},
+		{2, 32 * 1024, false},
+	} {
+		c := unavailableClient(
+			WithLocalFallback(1),
+			WithLocalFallbackLimits(tc.time, tc.memory))
+		defer c.Close()
+
+		valid, _, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+		if tc.ok {
+			require.NoError(t, err)
+			assert.True(t, valid, "valid")
+			continue
+		}
+
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err), "invalid gRPC status code")
+		assert.ErrorIs(t, err, ErrCostTooHigh)
+	}
+
+	assert.PanicsWithValue(t, "portunes: invalid local fallback limits", func() {
+		WithLocalFallbackLimits(0, 64*1024)
+	})
+}
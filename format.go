@@ -0,0 +1,169 @@
+package portunes
+
+import (
+	"context"
+	"crypto/subtle"
+)
+
+// hashFormat is a single version of the encoded hash
+// format. Each version is registered with registerFormat
+// under the number of trailing ones in its first varint.
+type hashFormat interface {
+	// decode parses hash, whose version has already been
+	// matched to this format. kr may be nil.
+	decode(kr *keyring, hash []byte) (*decodedHash, error)
+
+	// verify reports whether password and pepper match a
+	// hash previously returned from decode.
+	verify(ctx context.Context, dv deriver, d *decodedHash, password, pepper []byte) (bool, error)
+}
+
+// hashEncoder is implemented by formats that new hashes can
+// be encoded with.
+type hashEncoder interface {
+	encode(p *params, salt, tag []byte) []byte
+}
+
+// decodedHash is the result of decoding a hash.
+type decodedHash struct {
+	// format is the innermost format of the hash and is
+	// used to verify it.
+	format hashFormat
+
+	params
+	salt, tag []byte
+
+	// sealed is true if the hash was encrypted with the
+	// envelope key keyID.
+	sealed bool
+	keyID  uint32
+}
+
+var formats = make(map[int]hashFormat)
+
+// registerFormat registers f as the format for hashes of
+// version vers. It panics if vers is already registered.
+func registerFormat(vers int, f hashFormat) {
+	if _, dup := formats[vers]; dup {
+		panic("portunes: hash format registered twice")
+	}
+
+	formats[vers] = f
+}
+
+func init() {
+	registerFormat(paramsV0, argon2Format{})
+	registerFormat(paramsV1, argon2Format{})
+	registerFormat(envelopeV, envelopeFormat{})
+	registerFormat(paramsV3, argon2Format{})
+	registerFormat(paramsV4, argon2Format{})
+	registerFormat(paramsV5, argon2Format{})
+	registerFormat(paramsV6, argon2Format{})
+}
+
+// encodeHash returns the encoded hash for the given
+// parameters, salt and tag.
+func encodeHash(p *params, salt, tag []byte) []byte {
+	enc, ok := formats[int(p.vers)].(hashEncoder)
+	if !ok {
+		panic("portunes: hash version cannot be encoded")
+	}
+
+	return enc.encode(p, salt, tag)
+}
+
+// decodeHash decodes hash with the format registered for
+// its version. Envelopes are decrypted with kr, which may be
+// nil.
+//
+// Hashes with an unknown version return an Unimplemented
+// error, as they may have come from a newer server, while
+// malformed hashes return an InvalidArgument error.
+func decodeHash(kr *keyring, hash []byte) (*decodedHash, error) {
+	vers := hashVersion(hash)
+	if vers < 0 {
+		return nil, errHashMalformed()
+	}
+
+	f, ok := formats[vers]
+	if !ok {
+		return nil, errUnsupportedVersion(vers)
+	}
+
+	return f.decode(kr, hash)
+}
+
+// argon2Format is the plain argon2 format shared by
+// paramsV0, paramsV1 and paramsV3 through paramsV6. The
+// fields present are determined by appendParams and
+// consumeParams.
+type argon2Format struct{}
+
+func (argon2Format) encode(p *params, salt, tag []byte) []byte {
+	res := make([]byte, 0, maxParamsLength+len(p.legacySetting)+len(salt)+len(tag))
+	res = appendParams(res, p)
+	res = append(res, salt...)
+	return append(res, tag...)
+}
+
+// decode splits hash into its parameters, salt and tag.
+//
+// The returned salt has no spare capacity so the pepper can
+// be safely appended to it for older versions.
+func (f argon2Format) decode(_ *keyring, hash []byte) (*decodedHash, error) {
+	p, rest, ok := consumeParams(hash)
+	if !ok || uint64(len(rest)) != uint64(p.saltLen)+uint64(p.tagLen) {
+		return nil, errHashMalformed()
+	}
+
+	return &decodedHash{
+		format: f,
+
+		params: p,
+		salt:   rest[:p.saltLen:p.saltLen],
+		tag:    rest[p.saltLen:],
+	}, nil
+}
+
+func (argon2Format) verify(ctx context.Context, dv deriver, d *decodedHash, password, pepper []byte) (bool, error) {
+	expect, err := dv.deriveKey(ctx, password, pepper, d.salt, &d.params)
+	if err != nil {
+		return false, err
+	}
+
+	if dv.wipe {
+		defer wipe(expect)
+	}
+
+	return subtle.ConstantTimeCompare(expect, d.tag) == 1, nil
+}
+
+// envelopeFormat is a hash encrypted with kr.seal. It wraps
+// one of the other formats, which is used to verify it.
+type envelopeFormat struct{}
+
+func (envelopeFormat) decode(kr *keyring, hash []byte) (*decodedHash, error) {
+	inner, keyID, err := kr.open(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Envelopes are never nested.
+	if isEnvelope(inner) {
+		return nil, errHashMalformed()
+	}
+
+	d, err := decodeHash(nil, inner)
+	if err != nil {
+		return nil, err
+	}
+
+	d.sealed, d.keyID = true, keyID
+	return d, nil
+}
+
+func (envelopeFormat) verify(ctx context.Context, dv deriver, d *decodedHash, password, pepper []byte) (bool, error) {
+	// decode replaces the format with that of the inner
+	// hash, so this is never reached.
+	panic("portunes: envelopeFormat.verify called")
+}
@@ -0,0 +1,51 @@
+package portunes
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestVerifyUnknownVersion(t *testing.T) {
+	t.Parallel()
+
+	c, _, stop := testingClient()
+	defer stop()
+
+	for _, vers := range []int{7, 8, 31} {
+		hash := appendVarint32(nil, (1<<vers)-1)
+		hash = append(hash, make([]byte, 32)...)
+
+		_, _, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+		assert.Equal(t, codes.Unimplemented, status.Code(err), "invalid gRPC status code")
+		assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+		var perr *Error
+		if assert.ErrorAs(t, err, &perr) {
+			assert.Equal(t, strconv.Itoa(vers), perr.Metadata["version"], "version")
+		}
+	}
+
+	for _, hash := range [][]byte{
+		nil,
+		{0xff},
+		{0x00, 0x00},
+	} {
+		_, _, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+		assert.ErrorIs(t, err, ErrHashMalformed)
+		assert.NotErrorIs(t, err, ErrUnsupportedVersion)
+	}
+}
+
+func TestRegisterFormatDuplicate(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() {
+		registerFormat(paramsV0, argon2Format{})
+	})
+}
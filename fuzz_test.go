@@ -0,0 +1,111 @@
+//go:build go1.18
+// +build go1.18
+
+package portunes
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func FuzzConsumeVarint32(f *testing.F) {
+	f.Add([]byte{0x00})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0x0f})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0x10})
+	f.Add([]byte{0x80, 0x00})
+
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		v, rest, ok := consumeVarint32(buf)
+		if !ok {
+			return
+		}
+
+		enc := appendVarint32(nil, v)
+		if !bytes.Equal(enc, buf[:len(buf)-len(rest)]) {
+			t.Fatalf("%02x decoded to %d which encodes as %02x", buf, v, enc)
+		}
+	})
+}
+
+func FuzzConsumeParams(f *testing.F) {
+	for _, vector := range testVectors {
+		hash, err := hex.DecodeString(vector.hash)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(hash)
+	}
+
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		p, rest, ok := consumeParams(buf)
+		if !ok {
+			return
+		}
+
+		if p.time == 0 || p.threads == 0 {
+			t.Fatalf("%02x decoded to invalid params %+v", buf, p)
+		}
+
+		enc := appendParams(nil, &p)
+		if !bytes.Equal(enc, buf[:len(buf)-len(rest)]) {
+			t.Fatalf("%02x decoded to %+v which encodes as %02x", buf, p, enc)
+		}
+	})
+}
+
+func FuzzVerify(f *testing.F) {
+	kr := newKeyring(testEnvelopeKey1.ID, []EnvelopeKey{testEnvelopeKey1, testEnvelopeKey2})
+
+	for _, vector := range testVectors {
+		hash, err := hex.DecodeString(vector.hash)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		sealed, err := kr.seal(hash)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(hash)
+		f.Add(sealed)
+	}
+
+	dv := deriver{tracer: trace.NewNoopTracerProvider().Tracer(instrumentationName)}
+
+	f.Fuzz(func(t *testing.T, hash []byte) {
+		d, err := decodeHash(kr, hash)
+		switch status.Code(err) {
+		case codes.OK:
+		case codes.InvalidArgument, codes.Unimplemented, codes.FailedPrecondition:
+			return
+		default:
+			t.Fatalf("decodeHash returned unexpected error: %v", err)
+		}
+
+		if !d.sealed {
+			enc := encodeHash(&d.params, d.salt, d.tag)
+			if !bytes.Equal(enc, hash) {
+				t.Fatalf("%02x decoded to %+v which encodes as %02x", hash, d.params, enc)
+			}
+		}
+
+		// Only derive keys for cheap parameters so the
+		// fuzzer doesn't spend all its time in argon2.
+		if d.time > 2 || d.memory > 1024 || d.threads > 4 {
+			return
+		}
+
+		_, err = d.format.verify(context.Background(), dv, d, []byte("password🔐🔓"), []byte("🔑📋"))
+		if err != nil && status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("verify returned unexpected error: %v", err)
+		}
+	})
+}
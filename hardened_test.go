@@ -0,0 +1,140 @@
+package portunes
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "go.tmthrgd.dev/portunes/internal/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHardenedMemory(t *testing.T) {
+	t.Parallel()
+
+	for _, sopt := range [][]ServerOption{
+		{WithHardenedMemory()},
+		{WithHardenedMemory(), WithNormalization(NormalizeOpaqueString)},
+		{WithHardenedMemory(), WithEnvelopeKeys(testEnvelopeKey1.ID, testEnvelopeKey1)},
+	} {
+		c, s, stop := testingClient(sopt...)
+		defer stop()
+
+		hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+		require.NoError(t, err)
+
+		valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+		require.NoError(t, err)
+		assert.True(t, valid, "valid")
+		assert.False(t, rehash, "rehash")
+
+		valid, _, err = c.Verify(context.Background(), "wrong🔐🔓", []byte("🔑📋"), hash)
+		require.NoError(t, err)
+		assert.False(t, valid, "valid")
+
+		require.NoError(t, c.VerifyDummy(context.Background(), "password🔐🔓", []byte("🔑📋")))
+
+		// The request buffers are zeroed once the handler
+		// returns.
+		hreq := &pb.HashRequest{
+			Password: []byte("password🔐🔓"),
+			Pepper:   []byte("🔑📋"),
+		}
+		_, err = pbServer{s}.Hash(context.Background(), hreq)
+		require.NoError(t, err)
+		assert.Equal(t, make([]byte, len("password🔐🔓")), hreq.Password, "password")
+		assert.Equal(t, make([]byte, len("🔑📋")), hreq.Pepper, "pepper")
+
+		vreq := &pb.VerifyRequest{
+			Password: []byte("password🔐🔓"),
+			Pepper:   []byte("🔑📋"),
+			Hash:     append([]byte(nil), hash...),
+		}
+		resp, err := pbServer{s}.Verify(context.Background(), vreq)
+		require.NoError(t, err)
+		assert.True(t, resp.Valid, "valid")
+		assert.Equal(t, make([]byte, len("password🔐🔓")), vreq.Password, "password")
+		assert.Equal(t, make([]byte, len("🔑📋")), vreq.Pepper, "pepper")
+	}
+}
+
+func TestHardenedMemoryLegacy(t *testing.T) {
+	t.Parallel()
+
+	c, s, stop := testingClient(WithHardenedMemory())
+	defer stop()
+
+	legacy := []byte("$1$8sFt66rZ$Xd2dBKsS3mGAlqTyOk3Vx1")
+
+	hash, err := c.WrapLegacy(context.Background(), legacy, nil)
+	require.NoError(t, err)
+
+	valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", nil, hash)
+	require.NoError(t, err)
+	assert.True(t, valid, "valid")
+	assert.True(t, rehash, "rehash")
+
+	req := &pb.WrapLegacyRequest{LegacyHash: append([]byte(nil), legacy...)}
+	_, err = pbServer{s}.WrapLegacy(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, make([]byte, len(legacy)), req.LegacyHash, "legacy hash")
+}
+
+func TestLockEnvelopeKeys(t *testing.T) {
+	t.Parallel()
+
+	key := testEnvelopeKey1
+	key.Key = append([]byte(nil), key.Key...)
+
+	c, s, stop := testingClient(WithEnvelopeKeys(key.ID, key))
+	defer stop()
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	if err := s.LockEnvelopeKeys(); err != nil {
+		t.Skipf("LockEnvelopeKeys failed: %v", err)
+	}
+
+	// Locking is idempotent.
+	require.NoError(t, s.LockEnvelopeKeys())
+
+	assert.True(t, bytes.Equal(key.Key, testEnvelopeKey1.Key), "caller's key was modified")
+
+	valid, _, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+	assert.True(t, valid, "valid")
+
+	hash, err = c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	valid, _, err = c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+	assert.True(t, valid, "valid")
+
+	_, s, stop2 := testingClient()
+	defer stop2()
+	assert.NoError(t, s.LockEnvelopeKeys(), "no keys")
+}
+
+func TestPasswordInvalidUTF8(t *testing.T) {
+	t.Parallel()
+
+	c, _, stop := testingClient()
+	defer stop()
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", nil)
+	require.NoError(t, err)
+
+	_, err = c.Hash(context.Background(), "password\xff", nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+
+	_, _, err = c.Verify(context.Background(), "password\xff", nil, hash)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+
+	err = c.VerifyDummy(context.Background(), "password\xff", nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+}
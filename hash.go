@@ -0,0 +1,184 @@
+package portunes
+
+import (
+	"context"
+	"crypto/rand"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.tmthrgd.dev/portunes/internal/argon2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultSaltLen = 16
+	defaultTagLen  = 16
+
+	// These are the minimums from section 3.1 of RFC 9106.
+	minSaltLen = 8
+	minTagLen  = 4
+)
+
+type params struct {
+	vers uint8
+
+	time, memory uint32
+	threads      uint8
+
+	norm Normalization
+
+	saltLen, tagLen uint32
+
+	variant Variant
+
+	legacy        legacyScheme
+	legacySetting string
+}
+
+// setVersion sets p.vers to the oldest version that uses
+// the pepper as the argon2 secret and can encode p. Newer
+// versions are only used when needed, so that hashes remain
+// verifiable by older servers where possible.
+func (p *params) setVersion() {
+	switch {
+	case p.legacy != legacyNone:
+		p.vers = paramsV6
+	case p.variant != Argon2id:
+		p.vers = paramsV5
+	case p.saltLen != defaultSaltLen || p.tagLen != defaultTagLen:
+		p.vers = paramsV4
+	default:
+		p.vers = paramsV3
+	}
+}
+
+// newSalt returns a random salt for p.
+func newSalt(p *params) ([]byte, error) {
+	salt := make([]byte, p.saltLen)
+	_, err := rand.Read(salt)
+	return salt, err
+}
+
+// deriver derives argon2 tags from passwords.
+type deriver struct {
+	tracer trace.Tracer
+
+	// wipe causes the intermediate buffers derived from the
+	// password, including the argon2 memory, to be zeroed
+	// once they're no longer needed, see WithHardenedMemory.
+	wipe bool
+
+	// mem, if not nil, is used for the argon2 memory rather
+	// than allocating it, see WithMemoryBudget.
+	mem *argon2.Memory
+}
+
+// deriveKey normalizes password and derives the argon2 tag
+// for it. For onion hashes, the legacy hash of the password
+// is computed first and the tag is derived from that.
+//
+// password and pepper are not modified.
+func (dv deriver) deriveKey(ctx context.Context, password, pepper, salt []byte, p *params) ([]byte, error) {
+	pw, err := normalizePassword(password, p.norm)
+	if err != nil {
+		return nil, err
+	}
+
+	if dv.wipe {
+		defer wipe(pw)
+	}
+
+	return dv.deriveNormalizedKey(ctx, pw, pepper, salt, p)
+}
+
+// normalizePassword returns password normalized with n.
+// The result is always a copy.
+func normalizePassword(password []byte, n Normalization) ([]byte, error) {
+	pw, err := n.apply(password)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid password: "+err.Error())
+	}
+
+	return pw, nil
+}
+
+// deriveNormalizedKey is like deriveKey, but password must
+// already have been normalized with normalizePassword.
+func (dv deriver) deriveNormalizedKey(ctx context.Context, pw, pepper, salt []byte, p *params) ([]byte, error) {
+	if p.legacy != legacyNone {
+		legacy, err := dv.computeLegacy(ctx, pw, p)
+		if err != nil {
+			return nil, err
+		}
+
+		if dv.wipe {
+			defer wipe(legacy)
+		}
+
+		pw = legacy
+	}
+
+	return dv.argon2Key(ctx, pw, pepper, salt, p), nil
+}
+
+// computeLegacy returns the output of the legacy hash that
+// an onion hash wraps. It records a span for the scheme.
+func (dv deriver) computeLegacy(ctx context.Context, password []byte, p *params) ([]byte, error) {
+	h := &legacyHashers[p.legacy]
+
+	_, span := dv.tracer.Start(ctx, "portunes."+h.name)
+	defer span.End()
+
+	output, err := h.compute([]byte(p.legacySetting), password)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return output, nil
+}
+
+// argon2Key derives the argon2 tag for input. Depending on
+// the version of p, the pepper is either appended to the
+// salt or used as the argon2 secret value. It records a
+// span with the cost parameters used.
+func (dv deriver) argon2Key(ctx context.Context, input, pepper, salt []byte, p *params) []byte {
+	_, span := dv.tracer.Start(ctx, "portunes."+p.variant.String(),
+		trace.WithAttributes(paramsAttributes(p.time, p.memory, p.threads)...))
+	defer span.End()
+
+	secret := pepper
+	if p.vers < paramsV3 {
+		salt, secret = append(salt, pepper...), nil
+
+		// salt only has a new backing array if the pepper
+		// isn't empty.
+		if dv.wipe && len(pepper) != 0 {
+			defer wipe(salt)
+		}
+	}
+
+	switch {
+	case dv.mem != nil && dv.wipe:
+		return dv.mem.DeriveKeyAndWipe(p.variant.mode(), input, salt, secret, nil,
+			p.time, p.memory, p.threads, p.tagLen)
+	case dv.mem != nil:
+		return dv.mem.DeriveKey(p.variant.mode(), input, salt, secret, nil,
+			p.time, p.memory, p.threads, p.tagLen)
+	case dv.wipe:
+		return argon2.DeriveKeyAndWipe(p.variant.mode(), input, salt, secret, nil,
+			p.time, p.memory, p.threads, p.tagLen)
+	}
+
+	return argon2.DeriveKey(p.variant.mode(), input, salt, secret, nil,
+		p.time, p.memory, p.threads, p.tagLen)
+}
+
+// wipe zeroes b. It isn't inlined so that the compiler
+// can't remove it as a dead store.
+//
+//go:noinline
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,365 @@
+// Package htpasswd reads and writes htpasswd files, as used
+// by Apache and nginx, whose entries are portunes hashes.
+//
+// Entries are encoded as "$portunes$" followed by the
+// unpadded standard base64 encoding of the hash, in the
+// style of the PHC string format. Existing bcrypt ("$2y$"),
+// MD5 ("$apr1$" or "$1$") and SHA-1 ("{SHA}") entries can
+// also be verified so that files can be migrated as users
+// log in.
+package htpasswd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.tmthrgd.dev/portunes"
+	"go.tmthrgd.dev/portunes/internal/md5crypt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const prefix = "$portunes$"
+
+var (
+	// ErrUnsupportedHash is returned when verifying an
+	// entry with any other hash scheme, such as DES
+	// crypt(3), SHA-256 ("$5$") or SHA-512 ("$6$") crypt,
+	// or plain text.
+	ErrUnsupportedHash = errors.New("htpasswd: unsupported hash scheme")
+
+	// ErrInvalidHash is returned when an entry is
+	// malformed.
+	ErrInvalidHash = errors.New("htpasswd: invalid hash")
+)
+
+// EncodeHash returns the htpasswd entry for a portunes hash.
+func EncodeHash(hash []byte) string {
+	return prefix + base64.RawStdEncoding.EncodeToString(hash)
+}
+
+// DecodeHash returns the portunes hash from an entry
+// returned by EncodeHash.
+func DecodeHash(entry string) ([]byte, error) {
+	if !strings.HasPrefix(entry, prefix) {
+		return nil, ErrInvalidHash
+	}
+
+	hash, err := base64.RawStdEncoding.DecodeString(entry[len(prefix):])
+	if err != nil || len(hash) == 0 {
+		return nil, ErrInvalidHash
+	}
+
+	return hash, nil
+}
+
+// Verify reports whether password matches the htpasswd
+// entry.
+//
+// portunes entries are verified with c and pepper, while
+// bcrypt, MD5 and SHA-1 entries are verified locally and
+// ignore pepper. rehash is always true for a valid bcrypt,
+// MD5 or SHA-1 entry so that it can be replaced with a
+// portunes hash.
+func Verify(ctx context.Context, c *portunes.Client, entry, password string, pepper []byte) (valid, rehash bool, err error) {
+	switch {
+	case strings.HasPrefix(entry, prefix):
+		hash, err := DecodeHash(entry)
+		if err != nil {
+			return false, false, err
+		}
+
+		return c.Verify(ctx, password, pepper, hash)
+	case strings.HasPrefix(entry, "$2a$"),
+		strings.HasPrefix(entry, "$2b$"),
+		strings.HasPrefix(entry, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(entry), []byte(password))
+		switch err {
+		case nil:
+			return true, true, nil
+		case bcrypt.ErrMismatchedHashAndPassword:
+			return false, false, nil
+		default:
+			return false, false, ErrInvalidHash
+		}
+	case strings.HasPrefix(entry, md5crypt.MagicAPR1),
+		strings.HasPrefix(entry, md5crypt.MagicMD5):
+		setting, _, err := md5crypt.Split([]byte(entry))
+		if err != nil {
+			return false, false, ErrInvalidHash
+		}
+
+		hash, err := md5crypt.HashWithSetting(setting, []byte(password))
+		if err != nil {
+			return false, false, ErrInvalidHash
+		}
+
+		valid := subtle.ConstantTimeCompare(hash, []byte(entry)) == 1
+		return valid, valid, nil
+	case strings.HasPrefix(entry, "{SHA}"):
+		expect, err := base64.StdEncoding.DecodeString(entry[len("{SHA}"):])
+		if err != nil || len(expect) != sha1.Size {
+			return false, false, ErrInvalidHash
+		}
+
+		sum := sha1.Sum([]byte(password))
+		valid := subtle.ConstantTimeCompare(sum[:], expect) == 1
+		return valid, valid, nil
+	default:
+		return false, false, ErrUnsupportedHash
+	}
+}
+
+// File is an htpasswd file. It is safe for concurrent use.
+//
+// Comments, blank lines and the order of users are
+// preserved when the file is written back out.
+type File struct {
+	mu    sync.RWMutex
+	lines []string
+	users map[string]int
+}
+
+// New returns an empty File.
+func New() *File {
+	return &File{users: make(map[string]int)}
+}
+
+// Read parses an htpasswd file from r.
+func Read(r io.Reader) (*File, error) {
+	f := New()
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSuffix(s.Text(), "\r")
+		f.lines = append(f.lines, text)
+
+		if isComment(text) {
+			continue
+		}
+
+		user, _, ok := splitEntry(text)
+		if !ok {
+			return nil, fmt.Errorf("htpasswd: line %d: expected user:hash", line)
+		}
+
+		// Apache uses the first entry for a user.
+		if _, dup := f.users[user]; !dup {
+			f.users[user] = len(f.lines) - 1
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Load reads the named htpasswd file.
+func Load(name string) (*File, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Save atomically replaces the named file with f.
+func (f *File) Save(name string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// Keep the mode of an existing file, as it is likely
+	// to have been restricted.
+	mode := os.FileMode(0600)
+	if fi, err := os.Stat(name); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if _, err := f.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// WriteTo writes the file to w in the htpasswd format.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var buf bytes.Buffer
+	for _, line := range f.lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	return buf.WriteTo(w)
+}
+
+// Users returns the users in the file in the order they
+// appear.
+func (f *File) Users() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	users := make([]string, 0, len(f.users))
+	for i, line := range f.lines {
+		user, _, ok := splitEntry(line)
+		if idx, isUser := f.users[user]; ok && isUser && idx == i {
+			users = append(users, user)
+		}
+	}
+
+	return users
+}
+
+// Get returns the entry for user.
+func (f *File) Get(user string) (entry string, ok bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	idx, ok := f.users[user]
+	if !ok {
+		return "", false
+	}
+
+	_, entry, _ = splitEntry(f.lines[idx])
+	return entry, true
+}
+
+// Set adds user with entry, or replaces the existing entry
+// for user. entry should usually come from EncodeHash.
+func (f *File) Set(user, entry string) error {
+	if trimmed := strings.TrimSpace(user); trimmed == "" || trimmed[0] == '#' ||
+		strings.ContainsAny(user, ":\r\n") {
+		return fmt.Errorf("htpasswd: invalid user %q", user)
+	}
+
+	if strings.ContainsAny(entry, "\r\n") {
+		return ErrInvalidHash
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	line := user + ":" + entry
+	if idx, ok := f.users[user]; ok {
+		f.lines[idx] = line
+	} else {
+		f.users[user] = len(f.lines)
+		f.lines = append(f.lines, line)
+	}
+
+	return nil
+}
+
+// Delete removes every entry for user from the file. It
+// reports whether the user was present.
+func (f *File) Delete(user string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.users[user]; !ok {
+		return false
+	}
+
+	lines := f.lines[:0]
+	for i, line := range f.lines {
+		if u, _, ok := splitEntry(line); ok && u == user && !isComment(line) {
+			continue
+		}
+
+		if u, _, ok := splitEntry(line); ok && f.users[u] == i && !isComment(line) {
+			f.users[u] = len(lines)
+		}
+
+		lines = append(lines, line)
+	}
+
+	f.lines = lines
+	delete(f.users, user)
+	return true
+}
+
+// Authenticate verifies password for user with Verify. If
+// the entry should be rehashed, it is replaced with a new
+// portunes hash from c, and updated is true. The caller is
+// responsible for saving the file when updated is true.
+//
+// If user is not in the file, the password is checked
+// against a dummy hash with c so that the time taken
+// doesn't reveal whether the user exists.
+func (f *File) Authenticate(ctx context.Context, c *portunes.Client, user, password string, pepper []byte) (valid, updated bool, err error) {
+	entry, ok := f.Get(user)
+	if !ok {
+		return false, false, c.VerifyDummy(ctx, password, pepper)
+	}
+
+	valid, rehash, err := Verify(ctx, c, entry, password, pepper)
+	if err != nil || !valid || !rehash {
+		return valid, false, err
+	}
+
+	hash, err := c.Hash(ctx, password, pepper)
+	if err != nil {
+		// The password was still valid, so report that
+		// along with the error.
+		return true, false, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Don't clobber an entry that was changed while the
+	// new hash was being derived.
+	idx, ok := f.users[user]
+	if !ok || f.lines[idx] != user+":"+entry {
+		return true, false, nil
+	}
+
+	f.lines[idx] = user + ":" + EncodeHash(hash)
+	return true, true, nil
+}
+
+// isComment reports whether line is blank or a comment.
+func isComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || trimmed[0] == '#'
+}
+
+func splitEntry(line string) (user, entry string, ok bool) {
+	idx := strings.IndexByte(line, ':')
+	if idx <= 0 {
+		return "", "", false
+	}
+
+	return line[:idx], line[idx+1:], true
+}
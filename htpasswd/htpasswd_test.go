@@ -0,0 +1,180 @@
+package htpasswd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/portunes/portunestest"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testFile = `# managed by portunes
+alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=
+
+bob:$apr1$r31.....$HqJZimcKQFAMYayBlzkrA/
+alice:{SHA}ignored
+`
+
+func TestReadWrite(t *testing.T) {
+	t.Parallel()
+
+	f, err := Read(strings.NewReader(testFile))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"alice", "bob"}, f.Users())
+
+	entry, ok := f.Get("alice")
+	assert.True(t, ok, "Get")
+	assert.Equal(t, "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", entry)
+
+	var buf bytes.Buffer
+	_, err = f.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, testFile, buf.String())
+
+	require.NoError(t, f.Set("carol", EncodeHash([]byte{1, 2, 3})))
+	require.NoError(t, f.Set("bob", "{SHA}x"))
+	assert.True(t, f.Delete("alice"), "Delete")
+	assert.False(t, f.Delete("dave"), "Delete")
+
+	assert.Equal(t, []string{"bob", "carol"}, f.Users())
+
+	buf.Reset()
+	_, err = f.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "# managed by portunes\n\nbob:{SHA}x\ncarol:$portunes$AQID\n", buf.String())
+
+	for _, user := range []string{"", " ", "# x", "a:b", "a\nb"} {
+		assert.Error(t, f.Set(user, "{SHA}x"), "Set(%q)", user)
+	}
+
+	assert.Error(t, f.Set("eve", "{SHA}x\nmallory:{SHA}y"))
+
+	_, err = Read(strings.NewReader("alice\n"))
+	assert.Error(t, err)
+}
+
+func TestSave(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "htpasswd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "htpasswd")
+	require.NoError(t, ioutil.WriteFile(name, []byte(testFile), 0640))
+
+	f, err := Load(name)
+	require.NoError(t, err)
+
+	require.NoError(t, f.Set("carol", "{SHA}x"))
+	require.NoError(t, f.Save(name))
+
+	fi, err := os.Stat(name)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), fi.Mode().Perm())
+
+	f2, err := Load(name)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob", "carol"}, f2.Users())
+}
+
+func TestEncodeHash(t *testing.T) {
+	t.Parallel()
+
+	hash := []byte{0x17, 0x00, 0x01, 0x00, 0xff}
+	entry := EncodeHash(hash)
+	assert.Equal(t, "$portunes$FwABAP8", entry)
+
+	hash2, err := DecodeHash(entry)
+	require.NoError(t, err)
+	assert.Equal(t, hash, hash2)
+
+	for _, entry := range []string{"", "$portunes$", "$portunes$FwABAP8=", "{SHA}FwABAP8"} {
+		_, err := DecodeHash(entry)
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	s := portunestest.NewServer()
+	defer s.Close()
+
+	c := s.Client()
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("password🔐🔓"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		entry, password string
+		valid, rehash   bool
+		err             error
+	}{
+		{EncodeHash(hash), "password🔐🔓", true, false, nil},
+		{EncodeHash(hash), "wrong", false, false, nil},
+		{string(bcryptHash), "password🔐🔓", true, true, nil},
+		{strings.Replace(string(bcryptHash), "$2a$", "$2y$", 1), "password🔐🔓", true, true, nil},
+		{string(bcryptHash), "wrong", false, false, nil},
+		{"{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", "password", true, true, nil},
+		{"{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", "wrong", false, false, nil},
+		{"{SHA}W6ph5Mm5Pz8G", "password", false, false, ErrInvalidHash},
+		{"$2y$bad", "password", false, false, ErrInvalidHash},
+		{"$apr1$8sFt66rZ$/2KB/ChEot7Ge/1n068od/", "password🔐🔓", true, true, nil},
+		{"$apr1$8sFt66rZ$/2KB/ChEot7Ge/1n068od/", "wrong", false, false, nil},
+		{"$1$8sFt66rZ$Xd2dBKsS3mGAlqTyOk3Vx1", "password🔐🔓", true, true, nil},
+		{"$apr1$8sFt66rZ$/2KB/ChEot7Ge", "password", false, false, ErrInvalidHash},
+		{"$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZF2w1b4/6", "password", false, false, ErrUnsupportedHash},
+	} {
+		valid, rehash, err := Verify(context.Background(), c, tc.entry, tc.password, []byte("🔑📋"))
+		assert.Equal(t, tc.err, err, tc.entry)
+		assert.Equal(t, tc.valid, valid, "valid: %s", tc.entry)
+		assert.Equal(t, tc.rehash, rehash, "rehash: %s", tc.entry)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	s := portunestest.NewServer()
+	defer s.Close()
+
+	c := s.Client()
+
+	f, err := Read(strings.NewReader(testFile))
+	require.NoError(t, err)
+
+	valid, updated, err := f.Authenticate(context.Background(), c, "alice", "wrong", nil)
+	require.NoError(t, err)
+	assert.False(t, valid, "valid")
+	assert.False(t, updated, "updated")
+
+	valid, updated, err = f.Authenticate(context.Background(), c, "alice", "password", nil)
+	require.NoError(t, err)
+	assert.True(t, valid, "valid")
+	assert.True(t, updated, "updated")
+
+	entry, _ := f.Get("alice")
+	assert.True(t, strings.HasPrefix(entry, "$portunes$"), "migrated")
+
+	valid, updated, err = f.Authenticate(context.Background(), c, "alice", "password", nil)
+	require.NoError(t, err)
+	assert.True(t, valid, "valid")
+	assert.False(t, updated, "updated")
+
+	valid, updated, err = f.Authenticate(context.Background(), c, "mallory", "password", nil)
+	require.NoError(t, err)
+	assert.False(t, valid, "valid")
+	assert.False(t, updated, "updated")
+}
@@ -0,0 +1,122 @@
+// Package httpauth provides net/http middleware that
+// protects handlers with HTTP Basic authentication verified
+// by a portunes.Client.
+package httpauth
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"go.tmthrgd.dev/portunes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type userKey struct{}
+
+// User returns the authenticated user from the request
+// context of a handler wrapped by BasicAuth.
+func User(ctx context.Context) (user string, ok bool) {
+	user, ok = ctx.Value(userKey{}).(string)
+	return user, ok
+}
+
+type config struct {
+	realm   string
+	pepper  []byte
+	rehash  portunes.RehashFunc
+	errorFn func(r *http.Request, err error)
+}
+
+// Option allows changing the behaviour of BasicAuth.
+type Option func(*config)
+
+// WithRealm sets the realm sent in the WWW-Authenticate
+// header. It defaults to "Restricted".
+func WithRealm(realm string) Option {
+	return func(c *config) {
+		c.realm = realm
+	}
+}
+
+// WithPepper sets the pepper passed to Client.Verify.
+func WithPepper(pepper []byte) Option {
+	return func(c *config) {
+		c.pepper = pepper
+	}
+}
+
+// WithRehashFunc sets the function called to persist a new
+// hash when the server advises that a user's hash should be
+// rehashed. Without it, hashes are never upgraded.
+func WithRehashFunc(fn portunes.RehashFunc) Option {
+	return func(c *config) {
+		c.rehash = fn
+	}
+}
+
+// WithErrorFunc sets the function called with any error
+// that occurs while authenticating a request. By default
+// errors are logged with the log package.
+//
+// Requests that fail with an error are answered with a 503
+// Service Unavailable if the server couldn't be reached and
+// otherwise a 500 Internal Server Error, unless the
+// password was valid and only persisting the new hash
+// failed, in which case the request is allowed through.
+func WithErrorFunc(fn func(r *http.Request, err error)) Option {
+	return func(c *config) {
+		c.errorFn = fn
+	}
+}
+
+// BasicAuth returns middleware that requires requests to
+// carry HTTP Basic credentials that are valid for a user in
+// store, as verified by c.Authenticate.
+//
+// The authenticated user is available to the wrapped
+// handler through User.
+func BasicAuth(c *portunes.Client, store portunes.UserStore, opts ...Option) func(http.Handler) http.Handler {
+	cfg := &config{
+		realm: "Restricted",
+		errorFn: func(r *http.Request, err error) {
+			log.Printf("httpauth: failed to authenticate request for %s: %v", r.URL.Path, err)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	challenge := "Basic realm=" + strconv.Quote(cfg.realm) + `, charset="UTF-8"`
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, password, ok := r.BasicAuth()
+			if !ok {
+				w.Header().Set("WWW-Authenticate", challenge)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			valid, err := c.Authenticate(r.Context(), store, user, password, cfg.pepper, cfg.rehash)
+			if err != nil {
+				cfg.errorFn(r, err)
+			}
+
+			switch {
+			case valid:
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+			case status.Code(err) == codes.Unavailable:
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			case err != nil:
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			default:
+				w.Header().Set("WWW-Authenticate", challenge)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package httpauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/portunes"
+	"go.tmthrgd.dev/portunes/portunestest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type mapStore map[string][]byte
+
+func (s mapStore) LookupHash(ctx context.Context, user string) ([]byte, error) {
+	hash, ok := s[user]
+	if !ok {
+		return nil, portunes.ErrUnknownUser
+	}
+
+	return hash, nil
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	user, _ := User(r.Context())
+	w.Write([]byte("hello " + user))
+})
+
+func do(h http.Handler, user, password string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if user != "" {
+		r.SetBasicAuth(user, password)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestBasicAuth(t *testing.T) {
+	t.Parallel()
+
+	s := portunestest.NewServer()
+	defer s.Close()
+
+	c := s.Client()
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	store := mapStore{"alice": hash}
+	h := BasicAuth(c, store, WithPepper([]byte("🔑📋")), WithRealm("test"))(okHandler)
+
+	w := do(h, "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Basic realm="test", charset="UTF-8"`, w.Header().Get("WWW-Authenticate"))
+
+	w = do(h, "alice", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = do(h, "mallory", "password🔐🔓")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = do(h, "alice", "password🔐🔓")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello alice", w.Body.String())
+}
+
+func TestBasicAuthRehash(t *testing.T) {
+	t.Parallel()
+
+	f := portunestest.NewFake()
+	defer f.Close()
+
+	store := mapStore{"alice": []byte("old hash")}
+	f.SetVerifyFunc(func(password string, pepper, hash []byte) (valid, rehash bool, err error) {
+		return password == "password🔐🔓", true, nil
+	})
+
+	var rehashed []byte
+	h := BasicAuth(f.Client(), store, WithRehashFunc(func(ctx context.Context, user string, oldHash, newHash []byte) error {
+		assert.Equal(t, "alice", user)
+		assert.Equal(t, []byte("old hash"), oldHash)
+		rehashed = newHash
+		return nil
+	}))(okHandler)
+
+	w := do(h, "alice", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Nil(t, rehashed, "rehashed invalid password")
+
+	w = do(h, "alice", "password🔐🔓")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, portunestest.Hash("password🔐🔓", nil), rehashed)
+}
+
+func TestBasicAuthErrors(t *testing.T) {
+	t.Parallel()
+
+	f := portunestest.NewFake()
+	defer f.Close()
+
+	var errs []error
+	store := mapStore{"alice": portunestest.Hash("password🔐🔓", nil)}
+	h := BasicAuth(f.Client(), store, WithErrorFunc(func(r *http.Request, err error) {
+		errs = append(errs, err)
+	}))(okHandler)
+
+	f.SetError(status.Error(codes.Unavailable, "down for maintenance"))
+
+	w := do(h, "alice", "password🔐🔓")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	f.SetError(status.Error(codes.Internal, "oops"))
+
+	w = do(h, "alice", "password🔐🔓")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	assert.Len(t, errs, 2)
+}
@@ -0,0 +1,71 @@
+package portunes
+
+// HashInfo describes a hash, see Inspect.
+type HashInfo struct {
+	// Version is the version of the hash format.
+	Version int
+
+	// Encrypted is true if the hash was encrypted with the
+	// envelope key KeyID. The remaining fields are only set
+	// for hashes that aren't encrypted.
+	Encrypted bool
+	KeyID     uint32
+
+	Variant       Variant
+	Time, Memory  uint32
+	Threads       uint8
+	Normalization Normalization
+
+	SaltLength, TagLength uint32
+
+	// Legacy is the name of the legacy scheme, "bcrypt" or
+	// "md5crypt", wrapped by an onion hash, or empty.
+	Legacy string
+}
+
+// Inspect returns information about a hash returned from
+// Client.Hash or Client.WrapLegacy. It doesn't require a
+// connection to the server.
+//
+// Hashes with an unknown version return an error with the
+// codes.Unimplemented status code, while malformed hashes
+// return one with the codes.InvalidArgument status code.
+func Inspect(hash []byte) (*HashInfo, error) {
+	if isEnvelope(hash) {
+		keyID, err := envelopeKeyID(hash)
+		if err != nil {
+			return nil, err
+		}
+
+		return &HashInfo{
+			Version: envelopeV,
+
+			Encrypted: true,
+			KeyID:     keyID,
+		}, nil
+	}
+
+	d, err := decodeHash(nil, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &HashInfo{
+		Version: int(d.vers),
+
+		Variant:       d.variant,
+		Time:          d.time,
+		Memory:        d.memory,
+		Threads:       d.threads,
+		Normalization: d.norm,
+
+		SaltLength: d.saltLen,
+		TagLength:  d.tagLen,
+	}
+
+	if d.legacy != legacyNone {
+		info.Legacy = legacyHashers[d.legacy].name
+	}
+
+	return info, nil
+}
@@ -0,0 +1,389 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package argon2 implements the key derivation function Argon2.
+//
+// It is a fork of golang.org/x/crypto/argon2 that additionally exposes the
+// optional secret value K and associated data X inputs described in section
+// 3.1 of RFC 9106[1], and that allows the memory to be wiped after use or
+// reused between calls. Otherwise it is unchanged, see the original package
+// for documentation of the cost parameters.
+//
+// [1] https://www.rfc-editor.org/rfc/rfc9106.html#section-3.1
+package argon2
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// The Argon2 version implemented by this package.
+const Version = 0x13
+
+// Mode is an Argon2 variant. Its value is the type y from the
+// specification.
+type Mode int
+
+// These are the Argon2 variants.
+const (
+	Argon2d Mode = iota
+	Argon2i
+	Argon2id
+)
+
+const (
+	argon2d  = int(Argon2d)
+	argon2i  = int(Argon2i)
+	argon2id = int(Argon2id)
+)
+
+// Key derives a key from the password, salt, and cost parameters using Argon2i
+// returning a byte slice of length keyLen that can be used as cryptographic
+// key. The CPU cost and parallelism degree must be greater than zero.
+//
+// For example, you can get a derived key for e.g. AES-256 (which needs a
+// 32-byte key) by doing:
+//
+//	key := argon2.Key([]byte("some password"), salt, 3, 32*1024, 4, 32)
+//
+// The draft RFC recommends time=3, and memory=32*1024 is a sensible number.
+// If using that amount of memory (32 MB) is not possible in some contexts then
+// the time parameter can be increased to compensate.
+//
+// The time parameter specifies the number of passes over the memory and the
+// memory parameter specifies the size of the memory in KiB. For example
+// memory=32*1024 sets the memory cost to ~32 MB. The number of threads can be
+// adjusted to the number of available CPUs. The cost parameters should be
+// increased as memory latency and CPU parallelism increases. Remember to get a
+// good random salt.
+func Key(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	return deriveKey(argon2i, password, salt, nil, nil, time, memory, threads, keyLen, false)
+}
+
+// IDKey derives a key from the password, salt, and cost parameters using
+// Argon2id returning a byte slice of length keyLen that can be used as
+// cryptographic key. The CPU cost and parallelism degree must be greater than
+// zero.
+//
+// For example, you can get a derived key for e.g. AES-256 (which needs a
+// 32-byte key) by doing:
+//
+//	key := argon2.IDKey([]byte("some password"), salt, 1, 64*1024, 4, 32)
+//
+// The draft RFC recommends time=1, and memory=64*1024 is a sensible number.
+// If using that amount of memory (64 MB) is not possible in some contexts then
+// the time parameter can be increased to compensate.
+//
+// The time parameter specifies the number of passes over the memory and the
+// memory parameter specifies the size of the memory in KiB. For example
+// memory=64*1024 sets the memory cost to ~64 MB. The number of threads can be
+// adjusted to the numbers of available CPUs. The cost parameters should be
+// increased as memory latency and CPU parallelism increases. Remember to get a
+// good random salt.
+func IDKey(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	return deriveKey(argon2id, password, salt, nil, nil, time, memory, threads, keyLen, false)
+}
+
+// DeriveKey derives a key using the given Argon2 variant. Unlike Key and IDKey
+// it also takes the optional secret value K and associated data X inputs,
+// either of which may be nil.
+//
+// The secret is a keying input that is not stored with the hash, so a hash
+// cannot be attacked without it.
+func DeriveKey(mode Mode, password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	if mode < Argon2d || mode > Argon2id {
+		panic("argon2: invalid mode")
+	}
+
+	return deriveKey(int(mode), password, salt, secret, data, time, memory, threads, keyLen, false)
+}
+
+// DeriveKeyAndWipe is like DeriveKey but zeroes the memory it used, which is
+// derived from the password, before returning. This costs an extra pass over
+// the memory.
+func DeriveKeyAndWipe(mode Mode, password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	if mode < Argon2d || mode > Argon2id {
+		panic("argon2: invalid mode")
+	}
+
+	return deriveKey(int(mode), password, salt, secret, data, time, memory, threads, keyLen, true)
+}
+
+// Memory is Argon2 memory that can be reused across calls to DeriveKey to
+// avoid allocating it each time. A Memory must not be used concurrently.
+type Memory struct {
+	blocks []block
+}
+
+// NewMemory returns a Memory of the given number of 1 KiB blocks, as returned
+// by Blocks.
+func NewMemory(blocks uint32) *Memory {
+	return &Memory{blocks: make([]block, blocks)}
+}
+
+// Blocks returns the number of 1 KiB blocks of memory used for the memory and
+// threads cost parameters. It's memory rounded down to a multiple of
+// 4*threads, but no fewer than 8*threads.
+func Blocks(memory uint32, threads uint8) uint32 {
+	if threads < 1 {
+		panic("argon2: parallelism degree too low")
+	}
+
+	memory = memory / (syncPoints * uint32(threads)) * (syncPoints * uint32(threads))
+	if memory < 2*syncPoints*uint32(threads) {
+		memory = 2 * syncPoints * uint32(threads)
+	}
+	return memory
+}
+
+// Len returns the number of 1 KiB blocks in m.
+func (m *Memory) Len() uint32 {
+	return uint32(len(m.blocks))
+}
+
+// DeriveKey is like the DeriveKey function but uses m rather than allocating
+// new memory. m must have at least Blocks(memory, threads) blocks.
+func (m *Memory) DeriveKey(mode Mode, password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	if mode < Argon2d || mode > Argon2id {
+		panic("argon2: invalid mode")
+	}
+
+	return deriveKeyWithMemory(m.blocks, int(mode), password, salt, secret, data, time, memory, threads, keyLen, false)
+}
+
+// DeriveKeyAndWipe is like the DeriveKeyAndWipe function but uses m rather
+// than allocating new memory. m is zeroed before it returns.
+func (m *Memory) DeriveKeyAndWipe(mode Mode, password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	if mode < Argon2d || mode > Argon2id {
+		panic("argon2: invalid mode")
+	}
+
+	return deriveKeyWithMemory(m.blocks, int(mode), password, salt, secret, data, time, memory, threads, keyLen, true)
+}
+
+func deriveKey(mode int, password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32, wipe bool) []byte {
+	return deriveKeyWithMemory(nil, mode, password, salt, secret, data, time, memory, threads, keyLen, wipe)
+}
+
+// deriveKeyWithMemory derives a key using mem as the memory, which may hold
+// the blocks of an earlier call, or newly allocated memory if mem is nil.
+func deriveKeyWithMemory(mem []block, mode int, password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32, wipe bool) []byte {
+	if time < 1 {
+		panic("argon2: number of rounds too small")
+	}
+	if threads < 1 {
+		panic("argon2: parallelism degree too low")
+	}
+	h0 := initHash(password, salt, secret, data, time, memory, uint32(threads), keyLen, mode)
+
+	memory = Blocks(memory, threads)
+	if mem == nil {
+		mem = make([]block, memory)
+	} else if uint32(len(mem)) < memory {
+		panic("argon2: memory too small")
+	}
+	B := initBlocks(mem[:memory], &h0, uint32(threads), wipe)
+	processBlocks(B, time, memory, uint32(threads), mode)
+	key := extractKey(B, memory, uint32(threads), keyLen, wipe)
+	if wipe {
+		clearBytes(h0[:])
+		for i := range B {
+			B[i] = block{}
+		}
+	}
+	return key
+}
+
+// clearBytes zeroes b. It isn't inlined so that the compiler can't remove it
+// as a dead store.
+//
+//go:noinline
+func clearBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+const (
+	blockLength = 128
+	syncPoints  = 4
+)
+
+type block [blockLength]uint64
+
+func initHash(password, salt, key, data []byte, time, memory, threads, keyLen uint32, mode int) [blake2b.Size + 8]byte {
+	var (
+		h0     [blake2b.Size + 8]byte
+		params [24]byte
+		tmp    [4]byte
+	)
+
+	b2, _ := blake2b.New512(nil)
+	binary.LittleEndian.PutUint32(params[0:4], threads)
+	binary.LittleEndian.PutUint32(params[4:8], keyLen)
+	binary.LittleEndian.PutUint32(params[8:12], memory)
+	binary.LittleEndian.PutUint32(params[12:16], time)
+	binary.LittleEndian.PutUint32(params[16:20], uint32(Version))
+	binary.LittleEndian.PutUint32(params[20:24], uint32(mode))
+	b2.Write(params[:])
+	binary.LittleEndian.PutUint32(tmp[:], uint32(len(password)))
+	b2.Write(tmp[:])
+	b2.Write(password)
+	binary.LittleEndian.PutUint32(tmp[:], uint32(len(salt)))
+	b2.Write(tmp[:])
+	b2.Write(salt)
+	binary.LittleEndian.PutUint32(tmp[:], uint32(len(key)))
+	b2.Write(tmp[:])
+	b2.Write(key)
+	binary.LittleEndian.PutUint32(tmp[:], uint32(len(data)))
+	b2.Write(tmp[:])
+	b2.Write(data)
+	b2.Sum(h0[:0])
+	return h0
+}
+
+// initBlocks fills the first two blocks of each lane of B. The other blocks
+// are overwritten by the first pass, so B needn't be zeroed.
+func initBlocks(B []block, h0 *[blake2b.Size + 8]byte, threads uint32, wipe bool) []block {
+	var block0 [1024]byte
+	memory := uint32(len(B))
+	for lane := uint32(0); lane < threads; lane++ {
+		j := lane * (memory / threads)
+		binary.LittleEndian.PutUint32(h0[blake2b.Size+4:], lane)
+
+		binary.LittleEndian.PutUint32(h0[blake2b.Size:], 0)
+		blake2bHash(block0[:], h0[:])
+		for i := range B[j+0] {
+			B[j+0][i] = binary.LittleEndian.Uint64(block0[i*8:])
+		}
+
+		binary.LittleEndian.PutUint32(h0[blake2b.Size:], 1)
+		blake2bHash(block0[:], h0[:])
+		for i := range B[j+1] {
+			B[j+1][i] = binary.LittleEndian.Uint64(block0[i*8:])
+		}
+	}
+	if wipe {
+		clearBytes(block0[:])
+	}
+	return B
+}
+
+func processBlocks(B []block, time, memory, threads uint32, mode int) {
+	lanes := memory / threads
+	segments := lanes / syncPoints
+
+	processSegment := func(n, slice, lane uint32, wg *sync.WaitGroup) {
+		var addresses, in, zero block
+		if mode == argon2i || (mode == argon2id && n == 0 && slice < syncPoints/2) {
+			in[0] = uint64(n)
+			in[1] = uint64(lane)
+			in[2] = uint64(slice)
+			in[3] = uint64(memory)
+			in[4] = uint64(time)
+			in[5] = uint64(mode)
+		}
+
+		index := uint32(0)
+		if n == 0 && slice == 0 {
+			index = 2 // we have already generated the first two blocks
+			if mode == argon2i || mode == argon2id {
+				in[6]++
+				processBlock(&addresses, &in, &zero)
+				processBlock(&addresses, &addresses, &zero)
+			}
+		}
+
+		offset := lane*lanes + slice*segments + index
+		var random uint64
+		for index < segments {
+			prev := offset - 1
+			if index == 0 && slice == 0 {
+				prev += lanes // last block in lane
+			}
+			if mode == argon2i || (mode == argon2id && n == 0 && slice < syncPoints/2) {
+				if index%blockLength == 0 {
+					in[6]++
+					processBlock(&addresses, &in, &zero)
+					processBlock(&addresses, &addresses, &zero)
+				}
+				random = addresses[index%blockLength]
+			} else {
+				random = B[prev][0]
+			}
+			newOffset := indexAlpha(random, lanes, segments, threads, n, slice, lane, index)
+			if n == 0 {
+				// The first pass overwrites rather than XORs so
+				// that reused memory gives the same result.
+				processBlock(&B[offset], &B[prev], &B[newOffset])
+			} else {
+				processBlockXOR(&B[offset], &B[prev], &B[newOffset])
+			}
+			index, offset = index+1, offset+1
+		}
+		wg.Done()
+	}
+
+	for n := uint32(0); n < time; n++ {
+		for slice := uint32(0); slice < syncPoints; slice++ {
+			var wg sync.WaitGroup
+			for lane := uint32(0); lane < threads; lane++ {
+				wg.Add(1)
+				go processSegment(n, slice, lane, &wg)
+			}
+			wg.Wait()
+		}
+	}
+
+}
+
+func extractKey(B []block, memory, threads, keyLen uint32, wipe bool) []byte {
+	lanes := memory / threads
+	for lane := uint32(0); lane < threads-1; lane++ {
+		for i, v := range B[(lane*lanes)+lanes-1] {
+			B[memory-1][i] ^= v
+		}
+	}
+
+	var block [1024]byte
+	for i, v := range B[memory-1] {
+		binary.LittleEndian.PutUint64(block[i*8:], v)
+	}
+	key := make([]byte, keyLen)
+	blake2bHash(key, block[:])
+	if wipe {
+		clearBytes(block[:])
+	}
+	return key
+}
+
+func indexAlpha(rand uint64, lanes, segments, threads, n, slice, lane, index uint32) uint32 {
+	refLane := uint32(rand>>32) % threads
+	if n == 0 && slice == 0 {
+		refLane = lane
+	}
+	m, s := 3*segments, ((slice+1)%syncPoints)*segments
+	if lane == refLane {
+		m += index
+	}
+	if n == 0 {
+		m, s = slice*segments, 0
+		if slice == 0 || lane == refLane {
+			m += index
+		}
+	}
+	if index == 0 || lane == refLane {
+		m--
+	}
+	return phi(rand, uint64(m), uint64(s), refLane, lanes)
+}
+
+func phi(rand, m, s uint64, lane, lanes uint32) uint32 {
+	p := rand & 0xFFFFFFFF
+	p = (p * p) >> 32
+	p = (p * m) >> 32
+	return lane*lanes + uint32((s+m-(p+1))%uint64(lanes))
+}
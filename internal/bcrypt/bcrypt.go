@@ -0,0 +1,238 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bcrypt implements Provos and Mazières's bcrypt adaptive hashing
+// algorithm.
+//
+// It is a fork of golang.org/x/crypto/bcrypt that exposes computing a hash
+// from the setting, the version, cost and salt, of an existing hash. This
+// allows the setting to be stored separately from the output, as done when
+// wrapping a bcrypt hash in an Argon2 hash.
+package bcrypt
+
+// The code is a port of Provos and Mazières's C implementation.
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+const (
+	MinCost int = 4  // the minimum allowable cost
+	MaxCost int = 31 // the maximum allowable cost
+)
+
+// ErrInvalidHash is returned when a hash or setting is malformed.
+var ErrInvalidHash = errors.New("bcrypt: invalid hash")
+
+const (
+	majorVersion       = '2'
+	maxCryptedHashSize = 23
+	encodedSaltSize    = 22
+	encodedHashSize    = 31
+)
+
+// magicCipherData is an IV for the 64 Blowfish encryption calls in
+// bcrypt(). It's the string "OrpheanBeholderScryDoubt" in big-endian bytes.
+var magicCipherData = []byte{
+	0x4f, 0x72, 0x70, 0x68,
+	0x65, 0x61, 0x6e, 0x42,
+	0x65, 0x68, 0x6f, 0x6c,
+	0x64, 0x65, 0x72, 0x53,
+	0x63, 0x72, 0x79, 0x44,
+	0x6f, 0x75, 0x62, 0x74,
+}
+
+type hashed struct {
+	hash  []byte
+	salt  []byte
+	cost  int // allowed range is MinCost to MaxCost
+	major byte
+	minor byte
+}
+
+// Split splits a bcrypt hash into its setting, which holds the version, cost
+// and salt, and the encoded output.
+func Split(hash []byte) (setting, output []byte, err error) {
+	p := new(hashed)
+	n, err := p.decodeSetting(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(hash)-n != encodedHashSize {
+		return nil, nil, ErrInvalidHash
+	}
+
+	return hash[:n:n], hash[n:], nil
+}
+
+// Cost returns the cost from a bcrypt setting as returned by Split.
+func Cost(setting []byte) (int, error) {
+	p := new(hashed)
+	n, err := p.decodeSetting(setting)
+	if err != nil {
+		return 0, err
+	}
+
+	if n != len(setting) {
+		return 0, ErrInvalidHash
+	}
+
+	return p.cost, nil
+}
+
+// HashWithSetting returns the full bcrypt hash of password using the
+// setting from Split.
+func HashWithSetting(setting, password []byte) ([]byte, error) {
+	p := new(hashed)
+	n, err := p.decodeSetting(setting)
+	if err != nil {
+		return nil, err
+	}
+
+	if n != len(setting) {
+		return nil, ErrInvalidHash
+	}
+
+	p.hash, err = bcrypt(password, p.cost, p.salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Hash(), nil
+}
+
+// decodeSetting parses the version, cost and salt at the start of sbytes and
+// returns their length.
+func (p *hashed) decodeSetting(sbytes []byte) (int, error) {
+	n, err := p.decodeVersion(sbytes)
+	if err != nil {
+		return -1, err
+	}
+
+	m, err := p.decodeCost(sbytes[n:])
+	if err != nil {
+		return -1, err
+	}
+	n += m
+
+	if len(sbytes)-n < encodedSaltSize {
+		return -1, ErrInvalidHash
+	}
+
+	// The "+2" is here because we'll have to append at most 2 '=' to the salt
+	// when base64 decoding it in expensiveBlowfishSetup().
+	p.salt = make([]byte, encodedSaltSize, encodedSaltSize+2)
+	copy(p.salt, sbytes[n:n+encodedSaltSize])
+
+	if _, err := base64Decode(append(p.salt[:0:0], p.salt...)); err != nil {
+		return -1, ErrInvalidHash
+	}
+
+	return n + encodedSaltSize, nil
+}
+
+func bcrypt(password []byte, cost int, salt []byte) ([]byte, error) {
+	cipherData := make([]byte, len(magicCipherData))
+	copy(cipherData, magicCipherData)
+
+	c, err := expensiveBlowfishSetup(password, uint32(cost), salt)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < 24; i += 8 {
+		for j := 0; j < 64; j++ {
+			c.Encrypt(cipherData[i:i+8], cipherData[i:i+8])
+		}
+	}
+
+	// Bug compatibility with C bcrypt implementations. We only encode 23 of
+	// the 24 bytes encrypted.
+	hsh := base64Encode(cipherData[:maxCryptedHashSize])
+	return hsh, nil
+}
+
+func expensiveBlowfishSetup(key []byte, cost uint32, salt []byte) (*blowfish.Cipher, error) {
+	csalt, err := base64Decode(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bug compatibility with C bcrypt implementations. They use the trailing
+	// NULL in the key string during expansion.
+	// We copy the key to prevent changing the underlying array.
+	ckey := append(key[:len(key):len(key)], 0)
+
+	c, err := blowfish.NewSaltedCipher(ckey, csalt)
+	if err != nil {
+		return nil, err
+	}
+
+	var i, rounds uint64
+	rounds = 1 << cost
+	for i = 0; i < rounds; i++ {
+		blowfish.ExpandKey(ckey, c)
+		blowfish.ExpandKey(csalt, c)
+	}
+
+	return c, nil
+}
+
+func (p *hashed) Hash() []byte {
+	arr := make([]byte, 60)
+	arr[0] = '$'
+	arr[1] = p.major
+	n := 2
+	if p.minor != 0 {
+		arr[2] = p.minor
+		n = 3
+	}
+	arr[n] = '$'
+	n++
+	copy(arr[n:], []byte(fmt.Sprintf("%02d", p.cost)))
+	n += 2
+	arr[n] = '$'
+	n++
+	copy(arr[n:], p.salt)
+	n += encodedSaltSize
+	copy(arr[n:], p.hash)
+	n += encodedHashSize
+	return arr[:n]
+}
+
+func (p *hashed) decodeVersion(sbytes []byte) (int, error) {
+	if len(sbytes) < 3 || sbytes[0] != '$' || sbytes[1] != majorVersion {
+		return -1, ErrInvalidHash
+	}
+	p.major = sbytes[1]
+	n := 3
+	if sbytes[2] != '$' {
+		if len(sbytes) < 4 || sbytes[3] != '$' {
+			return -1, ErrInvalidHash
+		}
+		p.minor = sbytes[2]
+		n++
+	}
+	return n, nil
+}
+
+// sbytes should begin where decodeVersion left off.
+func (p *hashed) decodeCost(sbytes []byte) (int, error) {
+	if len(sbytes) < 3 || sbytes[2] != '$' {
+		return -1, ErrInvalidHash
+	}
+	cost, err := strconv.Atoi(string(sbytes[0:2]))
+	if err != nil {
+		return -1, ErrInvalidHash
+	}
+	if cost < MinCost || cost > MaxCost {
+		return -1, ErrInvalidHash
+	}
+	p.cost = cost
+	return 3, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bcrypt
+
+import (
+	"bytes"
+	"testing"
+
+	xbcrypt "golang.org/x/crypto/bcrypt"
+)
+
+func TestHashWithSetting(t *testing.T) {
+	for _, hash := range []string{
+		"$2a$10$XajjQvNhvvRt5GSeFk1xFeyqRrsxkhBkUiQeg0dt.wU1qD4aFDcga",
+	} {
+		setting, _, err := Split([]byte(hash))
+		if err != nil {
+			t.Fatalf("Split(%q): %v", hash, err)
+		}
+
+		got, err := HashWithSetting(setting, []byte("allmine"))
+		if err != nil {
+			t.Fatalf("HashWithSetting(%q): %v", setting, err)
+		}
+
+		if string(got) != hash {
+			t.Errorf("HashWithSetting(%q) = %q, want %q", setting, got, hash)
+		}
+	}
+
+	for _, cost := range []int{MinCost, MinCost + 1} {
+		hash, err := xbcrypt.GenerateFromPassword([]byte("password"), cost)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		setting, _, err := Split(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := HashWithSetting(setting, []byte("password"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, hash) {
+			t.Errorf("HashWithSetting(%q) = %q, want %q", setting, got, hash)
+		}
+
+		if c, err := Cost(setting); err != nil || c != cost {
+			t.Errorf("Cost(%q) = %d, %v, want %d", setting, c, err, cost)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"$",
+		"$2a",
+		"$3a$10$XajjQvNhvvRt5GSeFk1xFeyqRrsxkhBkUiQeg0dt.wU1qD4aFDcga",
+		"$2a$1$XajjQvNhvvRt5GSeFk1xFeyqRrsxkhBkUiQeg0dt.wU1qD4aFDcga",
+		"$2a$03$XajjQvNhvvRt5GSeFk1xFeyqRrsxkhBkUiQeg0dt.wU1qD4aFDcga",
+		"$2a$10$XajjQvNhvvRt5GSeFk1xFe",
+		"$2a$10$XajjQvNhvvRt5GSeFk1xFeyqRrsxkhBkUiQeg0dt.wU1qD4aFDcg",
+		"$2a$10$XajjQvNhvvRt5GSeFk1!FeyqRrsxkhBkUiQeg0dt.wU1qD4aFDcga",
+	} {
+		if _, _, err := Split([]byte(hash)); err == nil {
+			t.Errorf("Split(%q) succeeded", hash)
+		}
+	}
+
+	if _, err := HashWithSetting([]byte("$2a$10$XajjQvNhvvRt5GSeFk1xFe."), []byte("x")); err == nil {
+		t.Error("HashWithSetting accepted trailing data")
+	}
+}
+
+func TestCost(t *testing.T) {
+	if _, err := Cost([]byte("$2a$10$XajjQvNhvvRt5GSeFk1xFeyqRrsxkhBkUiQeg0dt.wU1qD4aFDcga")); err == nil {
+		t.Error("Cost accepted a full hash")
+	}
+}
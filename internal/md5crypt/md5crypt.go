@@ -0,0 +1,196 @@
+// Package md5crypt implements Poul-Henning Kamp's MD5-based
+// crypt, as used by "$1$" hashes, and Apache's "$apr1$"
+// variant of it which only differs in the magic prefix.
+//
+// Like internal/bcrypt, it allows the setting, the magic
+// prefix and salt, to be split from the output of a hash and
+// later used to recompute it.
+//
+// MD5-crypt is not a suitable password hash and is only
+// implemented so that existing hashes can be wrapped in an
+// Argon2 hash.
+package md5crypt
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+)
+
+// ErrInvalidHash is returned when a hash or setting is
+// malformed.
+var ErrInvalidHash = errors.New("md5crypt: invalid hash")
+
+const (
+	// MagicMD5 is the prefix of a standard MD5-crypt hash.
+	MagicMD5 = "$1$"
+	// MagicAPR1 is the prefix of an Apache MD5-crypt hash.
+	MagicAPR1 = "$apr1$"
+
+	maxSaltSize       = 8
+	encodedOutputSize = 22
+)
+
+const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Split splits an MD5-crypt hash into its setting, which
+// holds the magic prefix and salt, and the encoded output.
+func Split(hash []byte) (setting, output []byte, err error) {
+	n, err := decodeSetting(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(hash)-n != 1+encodedOutputSize || hash[n] != '$' ||
+		!validChars(hash[n+1:]) {
+		return nil, nil, ErrInvalidHash
+	}
+
+	return hash[:n:n], hash[n+1:], nil
+}
+
+// ValidSetting reports whether setting is a setting as
+// returned by Split.
+func ValidSetting(setting []byte) bool {
+	n, err := decodeSetting(setting)
+	return err == nil && n == len(setting)
+}
+
+// HashWithSetting returns the full MD5-crypt hash of
+// password using the setting from Split.
+func HashWithSetting(setting, password []byte) ([]byte, error) {
+	if !ValidSetting(setting) {
+		return nil, ErrInvalidHash
+	}
+
+	magic, salt := splitSetting(setting)
+	out := crypt(password, magic, salt)
+
+	hash := make([]byte, 0, len(setting)+1+encodedOutputSize)
+	hash = append(hash, setting...)
+	hash = append(hash, '$')
+	return append(hash, out...), nil
+}
+
+// decodeSetting parses the magic prefix and salt at the
+// start of hash and returns their length. The salt must be
+// between 1 and 8 characters from the crypt alphabet.
+func decodeSetting(hash []byte) (int, error) {
+	var magic string
+	switch {
+	case bytes.HasPrefix(hash, []byte(MagicMD5)):
+		magic = MagicMD5
+	case bytes.HasPrefix(hash, []byte(MagicAPR1)):
+		magic = MagicAPR1
+	default:
+		return 0, ErrInvalidHash
+	}
+
+	salt := hash[len(magic):]
+	if i := bytes.IndexByte(salt, '$'); i >= 0 {
+		salt = salt[:i]
+	}
+
+	if len(salt) == 0 || len(salt) > maxSaltSize || !validChars(salt) {
+		return 0, ErrInvalidHash
+	}
+
+	return len(magic) + len(salt), nil
+}
+
+func splitSetting(setting []byte) (magic, salt []byte) {
+	n := len(MagicMD5)
+	if bytes.HasPrefix(setting, []byte(MagicAPR1)) {
+		n = len(MagicAPR1)
+	}
+
+	return setting[:n], setting[n:]
+}
+
+func validChars(b []byte) bool {
+	for _, c := range b {
+		if bytes.IndexByte([]byte(itoa64), c) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// crypt returns the encoded output of MD5-crypt, following
+// the reference implementation in FreeBSD's crypt-md5.c.
+func crypt(password, magic, salt []byte) []byte {
+	alt := md5.New()
+	alt.Write(password)
+	alt.Write(salt)
+	alt.Write(password)
+	final := alt.Sum(nil)
+
+	ctx := md5.New()
+	ctx.Write(password)
+	ctx.Write(magic)
+	ctx.Write(salt)
+
+	for pl := len(password); pl > 0; pl -= md5.Size {
+		if pl > md5.Size {
+			ctx.Write(final)
+		} else {
+			ctx.Write(final[:pl])
+		}
+	}
+
+	for i := len(password); i != 0; i >>= 1 {
+		if i&1 != 0 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write(password[:1])
+		}
+	}
+
+	final = ctx.Sum(final[:0])
+
+	// This is meant to slow the hash down, though it no
+	// longer does so meaningfully.
+	for i := 0; i < 1000; i++ {
+		ctx := md5.New()
+		if i&1 != 0 {
+			ctx.Write(password)
+		} else {
+			ctx.Write(final)
+		}
+		if i%3 != 0 {
+			ctx.Write(salt)
+		}
+		if i%7 != 0 {
+			ctx.Write(password)
+		}
+		if i&1 != 0 {
+			ctx.Write(final)
+		} else {
+			ctx.Write(password)
+		}
+		final = ctx.Sum(final[:0])
+	}
+
+	out := make([]byte, 0, encodedOutputSize)
+	for _, g := range [...][3]int{
+		{0, 6, 12},
+		{1, 7, 13},
+		{2, 8, 14},
+		{3, 9, 15},
+		{4, 10, 5},
+	} {
+		out = to64(out, uint32(final[g[0]])<<16|uint32(final[g[1]])<<8|uint32(final[g[2]]), 4)
+	}
+
+	return to64(out, uint32(final[11]), 2)
+}
+
+func to64(out []byte, v uint32, n int) []byte {
+	for ; n > 0; n-- {
+		out = append(out, itoa64[v&0x3f])
+		v >>= 6
+	}
+
+	return out
+}
@@ -0,0 +1,74 @@
+package md5crypt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// These vectors were generated with openssl passwd -1 and
+// -apr1.
+var vectors = []struct {
+	password, hash string
+}{
+	{"password", "$1$saltstri$qQY4WxjABChYG1ccLpfkz/"},
+	{"", "$1$ab$rn6aQS/o7141mj179E/zA."},
+	{"a much longer password that exceeds sixteen bytes 🔐", "$1$x./9Zz$q/oW8R1jWa1FIRLfmyZOd."},
+	{"password", "$apr1$saltstri$KbmdckUzuN1qd7Gpo8DEL."},
+	{"", "$apr1$ab$S8K6Sgp3W8c9Jb6LxgywZ."},
+	{"a much longer password that exceeds sixteen bytes 🔐", "$apr1$x./9Zz$rmxBf7NcsUD35GickxzBb1"},
+}
+
+func TestHashWithSetting(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range vectors {
+		setting, output, err := Split([]byte(v.hash))
+		require.NoError(t, err, v.hash)
+		assert.Equal(t, v.hash, string(setting)+"$"+string(output))
+		assert.True(t, ValidSetting(setting), "ValidSetting(%q)", setting)
+
+		hash, err := HashWithSetting(setting, []byte(v.password))
+		require.NoError(t, err, v.hash)
+		assert.Equal(t, v.hash, string(hash))
+
+		hash, err = HashWithSetting(setting, []byte(v.password+"x"))
+		require.NoError(t, err, v.hash)
+		assert.NotEqual(t, v.hash, string(hash))
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, hash := range []string{
+		"",
+		"$1$",
+		"$1$$qQY4WxjABChYG1ccLpfkz/",
+		"$1$saltstrin$qQY4WxjABChYG1ccLpfkz/",
+		"$1$salt:$qQY4WxjABChYG1ccLpfkz/",
+		"$1$saltstri$qQY4WxjABChYG1ccLpfkz",
+		"$1$saltstri$qQY4WxjABChYG1ccLpfkz/x",
+		"$1$saltstri$qQY4WxjABChYG1ccLpfk:/",
+		"$1$saltstriqQY4WxjABChYG1ccLpfkz/",
+		"$apr$saltstri$KbmdckUzuN1qd7Gpo8DEL.",
+		"$2a$10$XajjQvNhvvRt5GSeFk1xFeyqRrsxkhBkUiQeg0dt.wU1qD4aFDcga",
+	} {
+		_, _, err := Split([]byte(hash))
+		assert.Equal(t, ErrInvalidHash, err, "Split(%q)", hash)
+	}
+
+	for _, setting := range []string{
+		"",
+		"$1$",
+		"$1$saltstri$",
+		"$1$saltstri$qQY4WxjABChYG1ccLpfkz/",
+		"$apr1$salt:",
+	} {
+		assert.False(t, ValidSetting([]byte(setting)), "ValidSetting(%q)", setting)
+
+		_, err := HashWithSetting([]byte(setting), []byte("password"))
+		assert.Equal(t, ErrInvalidHash, err, "HashWithSetting(%q)", setting)
+	}
+}
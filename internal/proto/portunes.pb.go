@@ -1,31 +1,18 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // source: portunes.proto
 
-/*
-Package proto is a generated protocol buffer package.
-
-It is generated from these files:
-	portunes.proto
-
-It has these top-level messages:
-	HashRequest
-	HashResponse
-	VerifyRequest
-	VerifyResponse
-*/
 package proto
 
-import proto1 "github.com/golang/protobuf/proto"
-import fmt "fmt"
-import math "math"
-
 import (
-	context "golang.org/x/net/context"
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
 	grpc "google.golang.org/grpc"
+	math "math"
 )
 
 // Reference imports to suppress errors if they are not otherwise used.
-var _ = proto1.Marshal
+var _ = proto.Marshal
 var _ = fmt.Errorf
 var _ = math.Inf
 
@@ -33,23 +20,83 @@ var _ = math.Inf
 // is compatible with the proto package it is being compiled against.
 // A compilation error at this line likely means your copy of the
 // proto package needs to be updated.
-const _ = proto1.ProtoPackageIsVersion2 // please upgrade the proto package
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type PolicyViolation_Reason int32
+
+const (
+	PolicyViolation_UNKNOWN             PolicyViolation_Reason = 0
+	PolicyViolation_TOO_SHORT           PolicyViolation_Reason = 1
+	PolicyViolation_TOO_LONG            PolicyViolation_Reason = 2
+	PolicyViolation_TOO_WEAK            PolicyViolation_Reason = 3
+	PolicyViolation_CONTAINS_USER_INPUT PolicyViolation_Reason = 4
+	PolicyViolation_BREACHED            PolicyViolation_Reason = 5
+)
+
+var PolicyViolation_Reason_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "TOO_SHORT",
+	2: "TOO_LONG",
+	3: "TOO_WEAK",
+	4: "CONTAINS_USER_INPUT",
+	5: "BREACHED",
+}
+
+var PolicyViolation_Reason_value = map[string]int32{
+	"UNKNOWN":             0,
+	"TOO_SHORT":           1,
+	"TOO_LONG":            2,
+	"TOO_WEAK":            3,
+	"CONTAINS_USER_INPUT": 4,
+	"BREACHED":            5,
+}
+
+func (x PolicyViolation_Reason) String() string {
+	return proto.EnumName(PolicyViolation_Reason_name, int32(x))
+}
+
+func (PolicyViolation_Reason) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_dd37752270238f47, []int{8, 0}
+}
 
 type HashRequest struct {
-	Password string `protobuf:"bytes,1,opt,name=password" json:"password,omitempty"`
-	Pepper   []byte `protobuf:"bytes,2,opt,name=pepper,proto3" json:"pepper,omitempty"`
+	Password             []byte   `protobuf:"bytes,1,opt,name=password,proto3" json:"password,omitempty"`
+	Pepper               []byte   `protobuf:"bytes,2,opt,name=pepper,proto3" json:"pepper,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HashRequest) Reset()         { *m = HashRequest{} }
+func (m *HashRequest) String() string { return proto.CompactTextString(m) }
+func (*HashRequest) ProtoMessage()    {}
+func (*HashRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd37752270238f47, []int{0}
 }
 
-func (m *HashRequest) Reset()                    { *m = HashRequest{} }
-func (m *HashRequest) String() string            { return proto1.CompactTextString(m) }
-func (*HashRequest) ProtoMessage()               {}
-func (*HashRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+func (m *HashRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HashRequest.Unmarshal(m, b)
+}
+func (m *HashRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HashRequest.Marshal(b, m, deterministic)
+}
+func (m *HashRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HashRequest.Merge(m, src)
+}
+func (m *HashRequest) XXX_Size() int {
+	return xxx_messageInfo_HashRequest.Size(m)
+}
+func (m *HashRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_HashRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HashRequest proto.InternalMessageInfo
 
-func (m *HashRequest) GetPassword() string {
+func (m *HashRequest) GetPassword() []byte {
 	if m != nil {
 		return m.Password
 	}
-	return ""
+	return nil
 }
 
 func (m *HashRequest) GetPepper() []byte {
@@ -60,13 +107,36 @@ func (m *HashRequest) GetPepper() []byte {
 }
 
 type HashResponse struct {
-	Hash []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Hash                 []byte   `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *HashResponse) Reset()         { *m = HashResponse{} }
+func (m *HashResponse) String() string { return proto.CompactTextString(m) }
+func (*HashResponse) ProtoMessage()    {}
+func (*HashResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd37752270238f47, []int{1}
+}
+
+func (m *HashResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HashResponse.Unmarshal(m, b)
+}
+func (m *HashResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HashResponse.Marshal(b, m, deterministic)
+}
+func (m *HashResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HashResponse.Merge(m, src)
+}
+func (m *HashResponse) XXX_Size() int {
+	return xxx_messageInfo_HashResponse.Size(m)
+}
+func (m *HashResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_HashResponse.DiscardUnknown(m)
 }
 
-func (m *HashResponse) Reset()                    { *m = HashResponse{} }
-func (m *HashResponse) String() string            { return proto1.CompactTextString(m) }
-func (*HashResponse) ProtoMessage()               {}
-func (*HashResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+var xxx_messageInfo_HashResponse proto.InternalMessageInfo
 
 func (m *HashResponse) GetHash() []byte {
 	if m != nil {
@@ -76,21 +146,44 @@ func (m *HashResponse) GetHash() []byte {
 }
 
 type VerifyRequest struct {
-	Password string `protobuf:"bytes,1,opt,name=password" json:"password,omitempty"`
-	Pepper   []byte `protobuf:"bytes,2,opt,name=pepper,proto3" json:"pepper,omitempty"`
-	Hash     []byte `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	Password             []byte   `protobuf:"bytes,1,opt,name=password,proto3" json:"password,omitempty"`
+	Pepper               []byte   `protobuf:"bytes,2,opt,name=pepper,proto3" json:"pepper,omitempty"`
+	Hash                 []byte   `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VerifyRequest) Reset()         { *m = VerifyRequest{} }
+func (m *VerifyRequest) String() string { return proto.CompactTextString(m) }
+func (*VerifyRequest) ProtoMessage()    {}
+func (*VerifyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd37752270238f47, []int{2}
+}
+
+func (m *VerifyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VerifyRequest.Unmarshal(m, b)
+}
+func (m *VerifyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VerifyRequest.Marshal(b, m, deterministic)
+}
+func (m *VerifyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VerifyRequest.Merge(m, src)
+}
+func (m *VerifyRequest) XXX_Size() int {
+	return xxx_messageInfo_VerifyRequest.Size(m)
+}
+func (m *VerifyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VerifyRequest.DiscardUnknown(m)
 }
 
-func (m *VerifyRequest) Reset()                    { *m = VerifyRequest{} }
-func (m *VerifyRequest) String() string            { return proto1.CompactTextString(m) }
-func (*VerifyRequest) ProtoMessage()               {}
-func (*VerifyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+var xxx_messageInfo_VerifyRequest proto.InternalMessageInfo
 
-func (m *VerifyRequest) GetPassword() string {
+func (m *VerifyRequest) GetPassword() []byte {
 	if m != nil {
 		return m.Password
 	}
-	return ""
+	return nil
 }
 
 func (m *VerifyRequest) GetPepper() []byte {
@@ -108,14 +201,37 @@ func (m *VerifyRequest) GetHash() []byte {
 }
 
 type VerifyResponse struct {
-	Valid  bool `protobuf:"varint,1,opt,name=valid" json:"valid,omitempty"`
-	Rehash bool `protobuf:"varint,2,opt,name=rehash" json:"rehash,omitempty"`
+	Valid                bool     `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	Rehash               bool     `protobuf:"varint,2,opt,name=rehash,proto3" json:"rehash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VerifyResponse) Reset()         { *m = VerifyResponse{} }
+func (m *VerifyResponse) String() string { return proto.CompactTextString(m) }
+func (*VerifyResponse) ProtoMessage()    {}
+func (*VerifyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd37752270238f47, []int{3}
 }
 
-func (m *VerifyResponse) Reset()                    { *m = VerifyResponse{} }
-func (m *VerifyResponse) String() string            { return proto1.CompactTextString(m) }
-func (*VerifyResponse) ProtoMessage()               {}
-func (*VerifyResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (m *VerifyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VerifyResponse.Unmarshal(m, b)
+}
+func (m *VerifyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VerifyResponse.Marshal(b, m, deterministic)
+}
+func (m *VerifyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VerifyResponse.Merge(m, src)
+}
+func (m *VerifyResponse) XXX_Size() int {
+	return xxx_messageInfo_VerifyResponse.Size(m)
+}
+func (m *VerifyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_VerifyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VerifyResponse proto.InternalMessageInfo
 
 func (m *VerifyResponse) GetValid() bool {
 	if m != nil {
@@ -131,11 +247,296 @@ func (m *VerifyResponse) GetRehash() bool {
 	return false
 }
 
+type VerifyDummyRequest struct {
+	Password             []byte   `protobuf:"bytes,1,opt,name=password,proto3" json:"password,omitempty"`
+	Pepper               []byte   `protobuf:"bytes,2,opt,name=pepper,proto3" json:"pepper,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VerifyDummyRequest) Reset()         { *m = VerifyDummyRequest{} }
+func (m *VerifyDummyRequest) String() string { return proto.CompactTextString(m) }
+func (*VerifyDummyRequest) ProtoMessage()    {}
+func (*VerifyDummyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd37752270238f47, []int{4}
+}
+
+func (m *VerifyDummyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VerifyDummyRequest.Unmarshal(m, b)
+}
+func (m *VerifyDummyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VerifyDummyRequest.Marshal(b, m, deterministic)
+}
+func (m *VerifyDummyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VerifyDummyRequest.Merge(m, src)
+}
+func (m *VerifyDummyRequest) XXX_Size() int {
+	return xxx_messageInfo_VerifyDummyRequest.Size(m)
+}
+func (m *VerifyDummyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VerifyDummyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VerifyDummyRequest proto.InternalMessageInfo
+
+func (m *VerifyDummyRequest) GetPassword() []byte {
+	if m != nil {
+		return m.Password
+	}
+	return nil
+}
+
+func (m *VerifyDummyRequest) GetPepper() []byte {
+	if m != nil {
+		return m.Pepper
+	}
+	return nil
+}
+
+type WrapLegacyRequest struct {
+	// A hash from a legacy password hashing scheme, bcrypt
+	// or MD5-crypt, to be wrapped in an onion hash.
+	LegacyHash           []byte   `protobuf:"bytes,1,opt,name=legacy_hash,json=legacyHash,proto3" json:"legacy_hash,omitempty"`
+	Pepper               []byte   `protobuf:"bytes,2,opt,name=pepper,proto3" json:"pepper,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WrapLegacyRequest) Reset()         { *m = WrapLegacyRequest{} }
+func (m *WrapLegacyRequest) String() string { return proto.CompactTextString(m) }
+func (*WrapLegacyRequest) ProtoMessage()    {}
+func (*WrapLegacyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd37752270238f47, []int{5}
+}
+
+func (m *WrapLegacyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WrapLegacyRequest.Unmarshal(m, b)
+}
+func (m *WrapLegacyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WrapLegacyRequest.Marshal(b, m, deterministic)
+}
+func (m *WrapLegacyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WrapLegacyRequest.Merge(m, src)
+}
+func (m *WrapLegacyRequest) XXX_Size() int {
+	return xxx_messageInfo_WrapLegacyRequest.Size(m)
+}
+func (m *WrapLegacyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WrapLegacyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WrapLegacyRequest proto.InternalMessageInfo
+
+func (m *WrapLegacyRequest) GetLegacyHash() []byte {
+	if m != nil {
+		return m.LegacyHash
+	}
+	return nil
+}
+
+func (m *WrapLegacyRequest) GetPepper() []byte {
+	if m != nil {
+		return m.Pepper
+	}
+	return nil
+}
+
+type CheckPolicyRequest struct {
+	Password string `protobuf:"bytes,1,opt,name=password,proto3" json:"password,omitempty"`
+	// Words related to the user, such as their username or
+	// email address, that should not be used in the password.
+	UserInputs           []string `protobuf:"bytes,2,rep,name=user_inputs,json=userInputs,proto3" json:"user_inputs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CheckPolicyRequest) Reset()         { *m = CheckPolicyRequest{} }
+func (m *CheckPolicyRequest) String() string { return proto.CompactTextString(m) }
+func (*CheckPolicyRequest) ProtoMessage()    {}
+func (*CheckPolicyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd37752270238f47, []int{6}
+}
+
+func (m *CheckPolicyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckPolicyRequest.Unmarshal(m, b)
+}
+func (m *CheckPolicyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckPolicyRequest.Marshal(b, m, deterministic)
+}
+func (m *CheckPolicyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckPolicyRequest.Merge(m, src)
+}
+func (m *CheckPolicyRequest) XXX_Size() int {
+	return xxx_messageInfo_CheckPolicyRequest.Size(m)
+}
+func (m *CheckPolicyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckPolicyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckPolicyRequest proto.InternalMessageInfo
+
+func (m *CheckPolicyRequest) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+func (m *CheckPolicyRequest) GetUserInputs() []string {
+	if m != nil {
+		return m.UserInputs
+	}
+	return nil
+}
+
+type CheckPolicyResponse struct {
+	Violations []*PolicyViolation `protobuf:"bytes,1,rep,name=violations,proto3" json:"violations,omitempty"`
+	// The estimated strength of the password in bits.
+	Entropy              float64  `protobuf:"fixed64,2,opt,name=entropy,proto3" json:"entropy,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CheckPolicyResponse) Reset()         { *m = CheckPolicyResponse{} }
+func (m *CheckPolicyResponse) String() string { return proto.CompactTextString(m) }
+func (*CheckPolicyResponse) ProtoMessage()    {}
+func (*CheckPolicyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd37752270238f47, []int{7}
+}
+
+func (m *CheckPolicyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckPolicyResponse.Unmarshal(m, b)
+}
+func (m *CheckPolicyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckPolicyResponse.Marshal(b, m, deterministic)
+}
+func (m *CheckPolicyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckPolicyResponse.Merge(m, src)
+}
+func (m *CheckPolicyResponse) XXX_Size() int {
+	return xxx_messageInfo_CheckPolicyResponse.Size(m)
+}
+func (m *CheckPolicyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckPolicyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckPolicyResponse proto.InternalMessageInfo
+
+func (m *CheckPolicyResponse) GetViolations() []*PolicyViolation {
+	if m != nil {
+		return m.Violations
+	}
+	return nil
+}
+
+func (m *CheckPolicyResponse) GetEntropy() float64 {
+	if m != nil {
+		return m.Entropy
+	}
+	return 0
+}
+
+type PolicyViolation struct {
+	Reason               PolicyViolation_Reason `protobuf:"varint,1,opt,name=reason,proto3,enum=portunes.PolicyViolation_Reason" json:"reason,omitempty"`
+	Message              string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *PolicyViolation) Reset()         { *m = PolicyViolation{} }
+func (m *PolicyViolation) String() string { return proto.CompactTextString(m) }
+func (*PolicyViolation) ProtoMessage()    {}
+func (*PolicyViolation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd37752270238f47, []int{8}
+}
+
+func (m *PolicyViolation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PolicyViolation.Unmarshal(m, b)
+}
+func (m *PolicyViolation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PolicyViolation.Marshal(b, m, deterministic)
+}
+func (m *PolicyViolation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PolicyViolation.Merge(m, src)
+}
+func (m *PolicyViolation) XXX_Size() int {
+	return xxx_messageInfo_PolicyViolation.Size(m)
+}
+func (m *PolicyViolation) XXX_DiscardUnknown() {
+	xxx_messageInfo_PolicyViolation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PolicyViolation proto.InternalMessageInfo
+
+func (m *PolicyViolation) GetReason() PolicyViolation_Reason {
+	if m != nil {
+		return m.Reason
+	}
+	return PolicyViolation_UNKNOWN
+}
+
+func (m *PolicyViolation) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
 func init() {
-	proto1.RegisterType((*HashRequest)(nil), "portunes.HashRequest")
-	proto1.RegisterType((*HashResponse)(nil), "portunes.HashResponse")
-	proto1.RegisterType((*VerifyRequest)(nil), "portunes.VerifyRequest")
-	proto1.RegisterType((*VerifyResponse)(nil), "portunes.VerifyResponse")
+	proto.RegisterEnum("portunes.PolicyViolation_Reason", PolicyViolation_Reason_name, PolicyViolation_Reason_value)
+	proto.RegisterType((*HashRequest)(nil), "portunes.HashRequest")
+	proto.RegisterType((*HashResponse)(nil), "portunes.HashResponse")
+	proto.RegisterType((*VerifyRequest)(nil), "portunes.VerifyRequest")
+	proto.RegisterType((*VerifyResponse)(nil), "portunes.VerifyResponse")
+	proto.RegisterType((*VerifyDummyRequest)(nil), "portunes.VerifyDummyRequest")
+	proto.RegisterType((*WrapLegacyRequest)(nil), "portunes.WrapLegacyRequest")
+	proto.RegisterType((*CheckPolicyRequest)(nil), "portunes.CheckPolicyRequest")
+	proto.RegisterType((*CheckPolicyResponse)(nil), "portunes.CheckPolicyResponse")
+	proto.RegisterType((*PolicyViolation)(nil), "portunes.PolicyViolation")
+}
+
+func init() { proto.RegisterFile("portunes.proto", fileDescriptor_dd37752270238f47) }
+
+var fileDescriptor_dd37752270238f47 = []byte{
+	// 520 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x54, 0x5d, 0x73, 0xd2, 0x40,
+	0x14, 0x6d, 0xf8, 0xe6, 0x86, 0x62, 0xdc, 0x6a, 0x1b, 0x51, 0xa7, 0xcc, 0x3e, 0xf1, 0xc4, 0x03,
+	0x3e, 0xa8, 0x0f, 0x3a, 0x43, 0x29, 0x53, 0x98, 0x32, 0x49, 0x5d, 0xa0, 0xcc, 0xf8, 0xc2, 0x44,
+	0x5c, 0x21, 0x0a, 0xd9, 0x75, 0x37, 0xa9, 0xd3, 0x3f, 0xe9, 0x2f, 0xf0, 0xc7, 0x38, 0xd9, 0x7c,
+	0x90, 0xc2, 0xd0, 0x97, 0x3e, 0x91, 0x73, 0xf7, 0xdc, 0x73, 0x0f, 0x37, 0x67, 0x03, 0x75, 0xce,
+	0x84, 0x1f, 0x78, 0x54, 0xb6, 0xb9, 0x60, 0x3e, 0x43, 0x95, 0x04, 0xe3, 0x2e, 0xe8, 0x03, 0x47,
+	0xae, 0x08, 0xfd, 0x1d, 0x50, 0xe9, 0xa3, 0x06, 0x54, 0xb8, 0x23, 0xe5, 0x1f, 0x26, 0xbe, 0x9b,
+	0x5a, 0x53, 0x6b, 0xd5, 0x48, 0x8a, 0xd1, 0x29, 0x94, 0x38, 0xe5, 0x9c, 0x0a, 0x33, 0xa7, 0x4e,
+	0x62, 0x84, 0x31, 0xd4, 0x22, 0x09, 0xc9, 0x99, 0x27, 0x29, 0x42, 0x50, 0x58, 0x39, 0x72, 0x15,
+	0xf7, 0xab, 0x67, 0x3c, 0x83, 0xe3, 0x5b, 0x2a, 0xdc, 0x1f, 0xf7, 0x4f, 0x18, 0x94, 0x0a, 0xe7,
+	0x33, 0xc2, 0x9f, 0xa1, 0x9e, 0x08, 0xc7, 0xe3, 0x5f, 0x40, 0xf1, 0xce, 0x59, 0xbb, 0x91, 0x6c,
+	0x85, 0x44, 0x20, 0xd4, 0x14, 0x54, 0x75, 0xe7, 0x54, 0x39, 0x46, 0x78, 0x00, 0x28, 0xea, 0xbf,
+	0x0c, 0x36, 0x9b, 0xa7, 0xb8, 0xc3, 0x23, 0x78, 0x3e, 0x13, 0x0e, 0x1f, 0xd1, 0xa5, 0xb3, 0x48,
+	0x85, 0xce, 0x41, 0x5f, 0xab, 0xc2, 0x3c, 0xb3, 0x12, 0x88, 0x4a, 0xe1, 0xd2, 0x0e, 0xaa, 0x7d,
+	0x01, 0xd4, 0x5b, 0xd1, 0xc5, 0xaf, 0x1b, 0xb6, 0x76, 0x17, 0x07, 0x7d, 0x55, 0x33, 0xbe, 0xce,
+	0x41, 0x0f, 0x24, 0x15, 0x73, 0xd7, 0xe3, 0x81, 0x2f, 0xcd, 0x5c, 0x33, 0xdf, 0xaa, 0x12, 0x08,
+	0x4b, 0x43, 0x55, 0xc1, 0x3f, 0xe1, 0xe4, 0x81, 0x64, 0xbc, 0xaf, 0x8f, 0x00, 0x77, 0x2e, 0x5b,
+	0x3b, 0xbe, 0xcb, 0x3c, 0x69, 0x6a, 0xcd, 0x7c, 0x4b, 0xef, 0xbc, 0x6a, 0xa7, 0x81, 0x89, 0xd8,
+	0xb7, 0x09, 0x83, 0x64, 0xc8, 0xc8, 0x84, 0x32, 0xf5, 0x7c, 0xc1, 0xf8, 0xbd, 0x72, 0xaf, 0x91,
+	0x04, 0xe2, 0xbf, 0x1a, 0x3c, 0xdb, 0xe9, 0x44, 0x1f, 0xc2, 0x57, 0xe0, 0x48, 0xe6, 0x29, 0xeb,
+	0xf5, 0x4e, 0xf3, 0xe0, 0x90, 0x36, 0x51, 0x3c, 0x12, 0xf3, 0xc3, 0x39, 0x1b, 0x2a, 0xa5, 0xb3,
+	0xa4, 0x6a, 0x4e, 0x95, 0x24, 0x10, 0x2f, 0xa1, 0x14, 0x71, 0x91, 0x0e, 0xe5, 0xa9, 0x75, 0x6d,
+	0xd9, 0x33, 0xcb, 0x38, 0x42, 0xc7, 0x50, 0x9d, 0xd8, 0xf6, 0x7c, 0x3c, 0xb0, 0xc9, 0xc4, 0xd0,
+	0x50, 0x0d, 0x2a, 0x21, 0x1c, 0xd9, 0xd6, 0x95, 0x91, 0x4b, 0xd0, 0xac, 0xdf, 0xbd, 0x36, 0xf2,
+	0xe8, 0x0c, 0x4e, 0x7a, 0xb6, 0x35, 0xe9, 0x0e, 0xad, 0xf1, 0x7c, 0x3a, 0xee, 0x93, 0xf9, 0xd0,
+	0xba, 0x99, 0x4e, 0x8c, 0x42, 0x48, 0xbb, 0x20, 0xfd, 0x6e, 0x6f, 0xd0, 0xbf, 0x34, 0x8a, 0x9d,
+	0x7f, 0x39, 0x28, 0x85, 0x2f, 0x8c, 0x0a, 0xf4, 0x1e, 0x0a, 0xe1, 0x13, 0x7a, 0xb9, 0xf5, 0x9f,
+	0xb9, 0x42, 0x8d, 0xd3, 0xdd, 0x72, 0xb4, 0x67, 0x7c, 0x84, 0x3e, 0x41, 0x29, 0xca, 0x1a, 0x3a,
+	0xdb, 0x72, 0x1e, 0x5c, 0x8b, 0x86, 0xb9, 0x7f, 0x90, 0xb6, 0x5f, 0x81, 0x9e, 0x89, 0x2a, 0x7a,
+	0xb3, 0x4b, 0xcd, 0x26, 0xf8, 0x51, 0xa1, 0x1e, 0xc0, 0x36, 0xa9, 0xe8, 0xf5, 0x96, 0xb9, 0x97,
+	0xdf, 0x47, 0xfe, 0xcc, 0x08, 0xf4, 0x4c, 0x9a, 0xb2, 0x6e, 0xf6, 0x73, 0xdb, 0x78, 0x7b, 0xe0,
+	0x34, 0x51, 0xbb, 0x28, 0x7f, 0x2d, 0xaa, 0x2f, 0xd3, 0xb7, 0x92, 0xfa, 0x79, 0xf7, 0x7f, 0x00,
+	0x6b, 0x96, 0x48, 0x65, 0xb2, 0x04, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -146,11 +547,15 @@ var _ grpc.ClientConn
 // is compatible with the grpc package it is being compiled against.
 const _ = grpc.SupportPackageIsVersion4
 
-// Client API for Hasher service
-
+// HasherClient is the client API for Hasher service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type HasherClient interface {
 	Hash(ctx context.Context, in *HashRequest, opts ...grpc.CallOption) (*HashResponse, error)
 	Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error)
+	VerifyDummy(ctx context.Context, in *VerifyDummyRequest, opts ...grpc.CallOption) (*VerifyResponse, error)
+	WrapLegacy(ctx context.Context, in *WrapLegacyRequest, opts ...grpc.CallOption) (*HashResponse, error)
+	CheckPolicy(ctx context.Context, in *CheckPolicyRequest, opts ...grpc.CallOption) (*CheckPolicyResponse, error)
 }
 
 type hasherClient struct {
@@ -163,7 +568,7 @@ func NewHasherClient(cc *grpc.ClientConn) HasherClient {
 
 func (c *hasherClient) Hash(ctx context.Context, in *HashRequest, opts ...grpc.CallOption) (*HashResponse, error) {
 	out := new(HashResponse)
-	err := grpc.Invoke(ctx, "/portunes.Hasher/Hash", in, out, c.cc, opts...)
+	err := c.cc.Invoke(ctx, "/portunes.Hasher/Hash", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -172,18 +577,47 @@ func (c *hasherClient) Hash(ctx context.Context, in *HashRequest, opts ...grpc.C
 
 func (c *hasherClient) Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error) {
 	out := new(VerifyResponse)
-	err := grpc.Invoke(ctx, "/portunes.Hasher/Verify", in, out, c.cc, opts...)
+	err := c.cc.Invoke(ctx, "/portunes.Hasher/Verify", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hasherClient) VerifyDummy(ctx context.Context, in *VerifyDummyRequest, opts ...grpc.CallOption) (*VerifyResponse, error) {
+	out := new(VerifyResponse)
+	err := c.cc.Invoke(ctx, "/portunes.Hasher/VerifyDummy", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hasherClient) WrapLegacy(ctx context.Context, in *WrapLegacyRequest, opts ...grpc.CallOption) (*HashResponse, error) {
+	out := new(HashResponse)
+	err := c.cc.Invoke(ctx, "/portunes.Hasher/WrapLegacy", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-// Server API for Hasher service
+func (c *hasherClient) CheckPolicy(ctx context.Context, in *CheckPolicyRequest, opts ...grpc.CallOption) (*CheckPolicyResponse, error) {
+	out := new(CheckPolicyResponse)
+	err := c.cc.Invoke(ctx, "/portunes.Hasher/CheckPolicy", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
 
+// HasherServer is the server API for Hasher service.
 type HasherServer interface {
 	Hash(context.Context, *HashRequest) (*HashResponse, error)
 	Verify(context.Context, *VerifyRequest) (*VerifyResponse, error)
+	VerifyDummy(context.Context, *VerifyDummyRequest) (*VerifyResponse, error)
+	WrapLegacy(context.Context, *WrapLegacyRequest) (*HashResponse, error)
+	CheckPolicy(context.Context, *CheckPolicyRequest) (*CheckPolicyResponse, error)
 }
 
 func RegisterHasherServer(s *grpc.Server, srv HasherServer) {
@@ -226,6 +660,60 @@ func _Hasher_Verify_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Hasher_VerifyDummy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyDummyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HasherServer).VerifyDummy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/portunes.Hasher/VerifyDummy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HasherServer).VerifyDummy(ctx, req.(*VerifyDummyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Hasher_WrapLegacy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WrapLegacyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HasherServer).WrapLegacy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/portunes.Hasher/WrapLegacy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HasherServer).WrapLegacy(ctx, req.(*WrapLegacyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Hasher_CheckPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckPolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HasherServer).CheckPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/portunes.Hasher/CheckPolicy",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HasherServer).CheckPolicy(ctx, req.(*CheckPolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Hasher_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "portunes.Hasher",
 	HandlerType: (*HasherServer)(nil),
@@ -238,28 +726,19 @@ var _Hasher_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Verify",
 			Handler:    _Hasher_Verify_Handler,
 		},
+		{
+			MethodName: "VerifyDummy",
+			Handler:    _Hasher_VerifyDummy_Handler,
+		},
+		{
+			MethodName: "WrapLegacy",
+			Handler:    _Hasher_WrapLegacy_Handler,
+		},
+		{
+			MethodName: "CheckPolicy",
+			Handler:    _Hasher_CheckPolicy_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "portunes.proto",
 }
-
-func init() { proto1.RegisterFile("portunes.proto", fileDescriptor0) }
-
-var fileDescriptor0 = []byte{
-	// 236 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2b, 0xc8, 0x2f, 0x2a,
-	0x29, 0xcd, 0x4b, 0x2d, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x80, 0xf1, 0x95, 0x1c,
-	0xb9, 0xb8, 0x3d, 0x12, 0x8b, 0x33, 0x82, 0x52, 0x0b, 0x4b, 0x53, 0x8b, 0x4b, 0x84, 0xa4, 0xb8,
-	0x38, 0x0a, 0x12, 0x8b, 0x8b, 0xcb, 0xf3, 0x8b, 0x52, 0x24, 0x18, 0x15, 0x18, 0x35, 0x38, 0x83,
-	0xe0, 0x7c, 0x21, 0x31, 0x2e, 0xb6, 0x82, 0xd4, 0x82, 0x82, 0xd4, 0x22, 0x09, 0x26, 0x05, 0x46,
-	0x0d, 0x9e, 0x20, 0x28, 0x4f, 0x49, 0x89, 0x8b, 0x07, 0x62, 0x44, 0x71, 0x41, 0x7e, 0x5e, 0x71,
-	0xaa, 0x90, 0x10, 0x17, 0x4b, 0x46, 0x62, 0x71, 0x06, 0x58, 0x3f, 0x4f, 0x10, 0x98, 0xad, 0x14,
-	0xce, 0xc5, 0x1b, 0x96, 0x5a, 0x94, 0x99, 0x56, 0x49, 0x81, 0x45, 0x70, 0x83, 0x99, 0x91, 0x0c,
-	0xb6, 0xe3, 0xe2, 0x83, 0x19, 0x0c, 0xb5, 0x5e, 0x84, 0x8b, 0xb5, 0x2c, 0x31, 0x27, 0x13, 0x62,
-	0x2c, 0x47, 0x10, 0x84, 0x03, 0x32, 0xb3, 0x28, 0x15, 0xac, 0x9b, 0x09, 0x2c, 0x0c, 0xe5, 0x19,
-	0x35, 0x30, 0x72, 0xb1, 0x81, 0x5c, 0x9f, 0x5a, 0x24, 0x64, 0xce, 0xc5, 0x02, 0x62, 0x09, 0x89,
-	0xea, 0xc1, 0x43, 0x0b, 0x29, 0x68, 0xa4, 0xc4, 0xd0, 0x85, 0x21, 0xf6, 0x29, 0x31, 0x08, 0xd9,
-	0x72, 0xb1, 0x41, 0xdc, 0x20, 0x24, 0x8e, 0x50, 0x83, 0xe2, 0x5d, 0x29, 0x09, 0x4c, 0x09, 0x98,
-	0x76, 0x27, 0x4e, 0x0f, 0xc6, 0x28, 0x56, 0x70, 0xbc, 0x24, 0xb1, 0x81, 0x29, 0x63, 0x40, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0x72, 0xf2, 0xce, 0xc5, 0xb0, 0x01, 0x00, 0x00,
-}
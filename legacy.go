@@ -0,0 +1,100 @@
+package portunes
+
+import (
+	"go.tmthrgd.dev/portunes/internal/bcrypt"
+	"go.tmthrgd.dev/portunes/internal/md5crypt"
+)
+
+// legacyScheme identifies the legacy password hash that an
+// onion hash wraps. The argon2 tag of an onion hash is
+// derived from the output of the legacy hash rather than
+// from the password directly, which allows existing hashes
+// to be strengthened without knowing the password.
+type legacyScheme uint8
+
+const (
+	legacyNone legacyScheme = iota
+	legacyBcrypt
+	legacyMD5Crypt
+
+	maxLegacyScheme = legacyMD5Crypt
+)
+
+// maxLegacySetting bounds the length of the setting stored
+// in an onion hash.
+const maxLegacySetting = 255
+
+// maxBcryptCost bounds the bcrypt cost of an onion hash. As
+// Verify accepts hashes from the caller and the cost isn't
+// covered by the DOS protection callback or the memory
+// budget, it's kept to what a single login can reasonably
+// spend, a few hundred milliseconds.
+const maxBcryptCost = 12
+
+type legacyHasher struct {
+	name string
+
+	// split returns the setting, which must be stored to
+	// recompute the legacy hash, and the output that argon2
+	// is derived from. It returns false if hash isn't of
+	// this scheme.
+	split func(hash []byte) (setting, output []byte, ok bool)
+
+	// validSetting reports whether setting came from split.
+	validSetting func(setting []byte) bool
+
+	// compute returns the output of the legacy hash for
+	// password using setting.
+	compute func(setting, password []byte) ([]byte, error)
+}
+
+var legacyHashers = [...]legacyHasher{
+	legacyBcrypt: {
+		name: "bcrypt",
+
+		split: func(hash []byte) (setting, output []byte, ok bool) {
+			setting, _, err := bcrypt.Split(hash)
+			if err != nil || !validBcryptSetting(setting) {
+				return nil, nil, false
+			}
+
+			// The argon2 input is the whole bcrypt hash.
+			return setting, hash, true
+		},
+		validSetting: validBcryptSetting,
+		compute:      bcrypt.HashWithSetting,
+	},
+	legacyMD5Crypt: {
+		name: "md5crypt",
+
+		split: func(hash []byte) (setting, output []byte, ok bool) {
+			setting, _, err := md5crypt.Split(hash)
+			if err != nil {
+				return nil, nil, false
+			}
+
+			// The argon2 input is the whole MD5-crypt hash,
+			// as with bcrypt.
+			return setting, hash, true
+		},
+		validSetting: md5crypt.ValidSetting,
+		compute:      md5crypt.HashWithSetting,
+	},
+}
+
+func validBcryptSetting(setting []byte) bool {
+	cost, err := bcrypt.Cost(setting)
+	return err == nil && cost <= maxBcryptCost
+}
+
+// splitLegacyHash identifies the scheme of a legacy hash
+// and splits it with that scheme.
+func splitLegacyHash(hash []byte) (scheme legacyScheme, setting, output []byte, ok bool) {
+	for scheme := legacyNone + 1; scheme <= maxLegacyScheme; scheme++ {
+		if setting, output, ok := legacyHashers[scheme].split(hash); ok {
+			return scheme, setting, output, true
+		}
+	}
+
+	return legacyNone, nil, nil, false
+}
@@ -0,0 +1,96 @@
+package portunes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashLengths(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name            string
+		saltLen, tagLen uint32
+	}{
+		{"8-4", 8, 4},
+		{"16-16", 16, 16},
+		{"16-32", 16, 32},
+		{"32-64", 32, 64},
+	} {
+		tc := tc // capture range variable
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, _, stop := testingClient(WithHashLengths(tc.saltLen, tc.tagLen))
+			defer stop()
+
+			hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+			require.NoError(t, err)
+
+			t.Logf("%d:%02x", len(hash), hash)
+
+			d, err := decodeHash(nil, hash)
+			require.NoError(t, err, "decodeHash")
+			p, salt, tag := d.params, d.salt, d.tag
+
+			assert.Len(t, salt, int(tc.saltLen), "salt length")
+			assert.Len(t, tag, int(tc.tagLen), "tag length")
+
+			if tc.saltLen == defaultSaltLen && tc.tagLen == defaultTagLen {
+				assert.Equal(t, uint8(paramsV3), p.vers, "params version")
+			} else {
+				assert.Equal(t, uint8(paramsV4), p.vers, "params version")
+			}
+
+			valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+			require.NoError(t, err)
+
+			assert.True(t, valid, "valid")
+			assert.False(t, rehash, "rehash")
+
+			valid, _, err = c.Verify(context.Background(), "wrong🔑📋", []byte("🔑📋"), hash)
+			require.NoError(t, err)
+
+			assert.False(t, valid, "valid")
+		})
+	}
+}
+
+func TestHashLengthsRehash(t *testing.T) {
+	t.Parallel()
+
+	c1, _, stop1 := testingClient()
+	defer stop1()
+
+	hash, err := c1.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	c2, _, stop2 := testingClient(WithHashLengths(16, 32))
+	defer stop2()
+
+	valid, rehash, err := c2.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.True(t, rehash, "rehash")
+
+	hash, err = c2.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	valid, rehash, err = c1.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.True(t, rehash, "rehash")
+}
+
+func TestHashLengthsInvalid(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() { WithHashLengths(7, 16) }, "short salt")
+	assert.Panics(t, func() { WithHashLengths(16, 3) }, "short tag")
+}
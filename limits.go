@@ -0,0 +1,97 @@
+package portunes
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// limits bounds the size of the inputs to argon2. A zero
+// value disables the check.
+type limits struct {
+	passwordBytes, passwordRunes int
+	pepperBytes                  int
+}
+
+// checkLimits returns an error if password or pepper exceed
+// the limits set with WithMaxPasswordLength or
+// WithMaxPepperLength. The error carries an
+// errdetails.BadRequest describing the violations.
+//
+// password must be valid UTF-8.
+func (s *Server) checkLimits(password, pepper []byte) error {
+	var violations []*errdetails.BadRequest_FieldViolation
+	violation := func(field, format string, args ...interface{}) {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: fmt.Sprintf(format, args...),
+		})
+	}
+
+	l := &s.limits
+	switch {
+	case l.passwordBytes > 0 && len(password) > l.passwordBytes:
+		violation("password", "password must be at most %d bytes", l.passwordBytes)
+	case l.passwordRunes > 0 && len(password) > l.passwordRunes &&
+		utf8.RuneCount(password) > l.passwordRunes:
+		violation("password", "password must be at most %d characters", l.passwordRunes)
+	}
+
+	if l.pepperBytes > 0 && len(pepper) > l.pepperBytes {
+		violation("pepper", "pepper must be at most %d bytes", l.pepperBytes)
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	st, err := status.New(codes.InvalidArgument, violations[0].Description).
+		WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return st.Err()
+}
+
+// WithMaxPasswordLength limits the length of the passwords
+// that Hash, Verify and VerifyDummy accept to maxBytes bytes
+// and maxRunes Unicode code points, before normalization.
+// Longer passwords are rejected with codes.InvalidArgument,
+// and an errdetails.BadRequest detail, before any argon2
+// work is done. A zero value disables either check.
+//
+// Unlike Policy.MaxLength, this also applies to Verify, so
+// it shouldn't be lowered below the length of passwords
+// that have already been hashed.
+//
+// By default the length of passwords isn't limited, beyond
+// the maximum message size of the grpc.Server.
+func WithMaxPasswordLength(maxBytes, maxRunes int) ServerOption {
+	if maxBytes < 0 || maxRunes < 0 {
+		panic("portunes: invalid maximum password length")
+	}
+
+	return func(s *Server) {
+		s.limits.passwordBytes, s.limits.passwordRunes = maxBytes, maxRunes
+	}
+}
+
+// WithMaxPepperLength limits the length of the peppers that
+// Hash, Verify, VerifyDummy and WrapLegacy accept to
+// maxBytes bytes in the same way as WithMaxPasswordLength.
+// A zero value disables the check.
+//
+// By default the length of peppers isn't limited.
+func WithMaxPepperLength(maxBytes int) ServerOption {
+	if maxBytes < 0 {
+		panic("portunes: invalid maximum pepper length")
+	}
+
+	return func(s *Server) {
+		s.limits.pepperBytes = maxBytes
+	}
+}
@@ -0,0 +1,97 @@
+package portunes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func requireBadRequest(t *testing.T, err error, field string) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	require.True(t, ok, "not a gRPC status error: %v", err)
+	assert.Equal(t, codes.InvalidArgument, st.Code(), "invalid gRPC status code")
+
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			require.Len(t, br.FieldViolations, 1)
+			assert.Equal(t, field, br.FieldViolations[0].Field)
+			return
+		}
+	}
+
+	t.Fatal("missing errdetails.BadRequest detail")
+}
+
+func TestMaxPasswordLength(t *testing.T) {
+	t.Parallel()
+
+	c, _, stop := testingClient(
+		WithMaxPasswordLength(32, 10),
+		WithMaxPepperLength(8))
+	defer stop()
+
+	pepper := []byte("🔑📋")
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", pepper)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		password string
+		pepper   []byte
+		field    string
+	}{
+		{"too many bytes", strings.Repeat("🔐", 9), pepper, "password"},
+		{"too many runes", strings.Repeat("a", 11), pepper, "password"},
+		{"pepper too long", "password🔐🔓", []byte("🔑📋🔑"), "pepper"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := c.Hash(context.Background(), tc.password, tc.pepper)
+			requireBadRequest(t, err, tc.field)
+
+			_, _, err = c.Verify(context.Background(), tc.password, tc.pepper, hash)
+			requireBadRequest(t, err, tc.field)
+
+			err = c.VerifyDummy(context.Background(), tc.password, tc.pepper)
+			requireBadRequest(t, err, tc.field)
+		})
+	}
+
+	_, err = c.WrapLegacy(context.Background(), []byte("$apr1$8sFt66rZ$/2KB/ChEot7Ge/1n068od/"), []byte("🔑📋🔑"))
+	requireBadRequest(t, err, "pepper")
+
+	// Exactly at the limits.
+	for _, password := range []string{
+		strings.Repeat("a", 10),
+		strings.Repeat("🔐", 8),
+	} {
+		hash, err := c.Hash(context.Background(), password, pepper)
+		require.NoError(t, err)
+
+		valid, _, err := c.Verify(context.Background(), password, pepper, hash)
+		require.NoError(t, err)
+		assert.True(t, valid, "valid")
+	}
+}
+
+func TestMaxPasswordLengthInvalid(t *testing.T) {
+	t.Parallel()
+
+	assert.PanicsWithValue(t, "portunes: invalid maximum password length", func() {
+		WithMaxPasswordLength(-1, 0)
+	})
+	assert.PanicsWithValue(t, "portunes: invalid maximum password length", func() {
+		WithMaxPasswordLength(0, -1)
+	})
+	assert.PanicsWithValue(t, "portunes: invalid maximum pepper length", func() {
+		WithMaxPepperLength(-1)
+	})
+}
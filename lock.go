@@ -0,0 +1,52 @@
+package portunes
+
+// LockEnvelopeKeys moves the keys given to WithEnvelopeKeys
+// into memory that is locked into RAM, so that they're never
+// written to swap, and that on Linux is excluded from core
+// dumps. The server's copies of the keys are zeroed.
+//
+// It must not be called while requests are being handled,
+// so should be called before Attach. It returns an error if
+// the memory couldn't be locked, such as if RLIMIT_MEMLOCK
+// is too low, or if locking memory isn't supported on this
+// platform. It does nothing if no keys were given.
+//
+// The AEADs keep their own copy of the expanded key while a
+// hash is being encrypted or decrypted which isn't locked.
+func (s *Server) LockEnvelopeKeys() error {
+	if s.keys == nil {
+		return nil
+	}
+
+	return s.keys.lock()
+}
+
+// lock moves the keys into locked memory, see
+// LockEnvelopeKeys. The memory is never freed.
+func (kr *keyring) lock() error {
+	if kr.locked {
+		return nil
+	}
+
+	var n int
+	for _, key := range kr.keys {
+		n += len(key.Key)
+	}
+
+	buf, err := allocLocked(n)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range kr.keys {
+		locked := buf[:len(key.Key):len(key.Key)]
+		buf = buf[len(key.Key):]
+
+		copy(locked, key.Key)
+		wipe(key.Key)
+		key.Key = locked
+	}
+
+	kr.locked = true
+	return nil
+}
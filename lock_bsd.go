@@ -0,0 +1,11 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package portunes
+
+// excludeFromCoreDump does nothing as there's no portable
+// equivalent of MADV_DONTDUMP. Core dumps should instead be
+// disabled entirely with RLIMIT_CORE.
+func excludeFromCoreDump(b []byte) error {
+	return nil
+}
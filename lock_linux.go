@@ -0,0 +1,7 @@
+package portunes
+
+import "golang.org/x/sys/unix"
+
+func excludeFromCoreDump(b []byte) error {
+	return unix.Madvise(b, unix.MADV_DONTDUMP)
+}
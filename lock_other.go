@@ -0,0 +1,10 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package portunes
+
+import "errors"
+
+func allocLocked(n int) ([]byte, error) {
+	return nil, errors.New("portunes: locking memory is not supported on this platform")
+}
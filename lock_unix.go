@@ -0,0 +1,29 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package portunes
+
+import "golang.org/x/sys/unix"
+
+// allocLocked returns n bytes of memory outside of the Go
+// heap that are locked into RAM and, where supported,
+// excluded from core dumps.
+func allocLocked(n int) ([]byte, error) {
+	b, err := unix.Mmap(-1, 0, n, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := unix.Mlock(b); err != nil {
+		unix.Munmap(b)
+		return nil, err
+	}
+
+	if err := excludeFromCoreDump(b); err != nil {
+		unix.Munlock(b)
+		unix.Munmap(b)
+		return nil, err
+	}
+
+	return b, nil
+}
@@ -0,0 +1,51 @@
+package portunes
+
+import (
+	"golang.org/x/text/secure/precis"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization specifies how passwords are normalized
+// before being hashed.
+//
+// The normalization used is recorded in the hash so that
+// Verify always applies the same normalization the hash
+// was created with.
+type Normalization uint8
+
+const (
+	// NormalizeNone hashes the password exactly as it
+	// was given.
+	NormalizeNone Normalization = iota
+	// NormalizeNFC applies Unicode Normalization Form C
+	// (canonical composition).
+	NormalizeNFC
+	// NormalizeNFKC applies Unicode Normalization Form
+	// KC (compatibility composition).
+	NormalizeNFKC
+	// NormalizeOpaqueString enforces the OpaqueString
+	// profile from RFC 8265. Non-ASCII spaces are mapped
+	// to ASCII spaces, the result is in NFC, and passwords
+	// containing disallowed code points are rejected.
+	NormalizeOpaqueString
+
+	maxNormalization = NormalizeOpaqueString
+)
+
+// apply returns the normalized form of password. It always
+// returns a new slice so that the result can be wiped
+// without modifying password.
+func (n Normalization) apply(password []byte) ([]byte, error) {
+	switch n {
+	case NormalizeNone:
+		return append([]byte(nil), password...), nil
+	case NormalizeNFC:
+		return norm.NFC.Append(nil, password...), nil
+	case NormalizeNFKC:
+		return norm.NFKC.Append(nil, password...), nil
+	case NormalizeOpaqueString:
+		return precis.OpaqueString.Append(nil, password)
+	default:
+		panic("portunes: invalid normalization")
+	}
+}
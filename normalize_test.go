@@ -0,0 +1,91 @@
+package portunes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	composedPassword   = "café🔐🔓"
+	decomposedPassword = "café🔐🔓"
+)
+
+func TestNormalization(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		norm  Normalization
+		equal bool
+	}{
+		{"None", NormalizeNone, false},
+		{"NFC", NormalizeNFC, true},
+		{"NFKC", NormalizeNFKC, true},
+		{"OpaqueString", NormalizeOpaqueString, true},
+	} {
+		tc := tc // capture range variable
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, _, stop := testingClient(WithNormalization(tc.norm))
+			defer stop()
+
+			hash, err := c.Hash(context.Background(), composedPassword, []byte("🔑📋"))
+			require.NoError(t, err)
+
+			t.Logf("%d:%02x", len(hash), hash)
+
+			valid, rehash, err := c.Verify(context.Background(), decomposedPassword, []byte("🔑📋"), hash)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.equal, valid, "valid")
+			assert.False(t, rehash, "rehash")
+		})
+	}
+}
+
+func TestNormalizationRecorded(t *testing.T) {
+	t.Parallel()
+
+	c1, _, stop1 := testingClient(WithNormalization(NormalizeNFC))
+	defer stop1()
+
+	hash, err := c1.Hash(context.Background(), composedPassword, []byte("🔑📋"))
+	require.NoError(t, err)
+
+	c2, _, stop2 := testingClient()
+	defer stop2()
+
+	valid, rehash, err := c2.Verify(context.Background(), decomposedPassword, []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.True(t, rehash, "rehash")
+
+	hash, err = c2.Hash(context.Background(), composedPassword, []byte("🔑📋"))
+	require.NoError(t, err)
+
+	valid, rehash, err = c1.Verify(context.Background(), composedPassword, []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.True(t, rehash, "rehash")
+}
+
+func TestNormalizationInvalid(t *testing.T) {
+	t.Parallel()
+
+	c, _, stop := testingClient(WithNormalization(NormalizeOpaqueString))
+	defer stop()
+
+	_, err := c.Hash(context.Background(), "pass\x00word", []byte("🔑📋"))
+	require.Error(t, err)
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+}
@@ -17,40 +17,172 @@ func consumeVarint32(buf []byte) (uint32, []byte, bool) {
 		return 0, nil, false
 	}
 
+	// Reject overlong encodings so that every value has
+	// exactly one valid encoding.
+	if n > 1 && buf[n-1] == 0 {
+		return 0, nil, false
+	}
+
 	return uint32(tmp), buf[n:], true
 }
 
-const maxParamsLength = 2 + 2*binary.MaxVarintLen32
+// maxParamsLength is the maximum length of the encoded
+// params, excluding any legacy setting.
+const maxParamsLength = 4 + 7*binary.MaxVarintLen32
+
+const (
+	// paramsV0 encodes the argon2 cost parameters. The
+	// pepper is appended to the salt.
+	paramsV0 = iota
+	// paramsV1 adds the password normalization.
+	paramsV1
+	// envelopeV marks a hash that has been encrypted with
+	// a server key, see envelope.go.
+	envelopeV
+	// paramsV3 has the same fields as paramsV1, but the
+	// pepper is used as the argon2 secret value K.
+	paramsV3
+	// paramsV4 adds the salt and tag lengths to paramsV3.
+	// Earlier versions always use 16-byte salts and tags.
+	paramsV4
+	// paramsV5 adds the argon2 variant to paramsV4.
+	// Earlier versions always use argon2id.
+	paramsV5
+	// paramsV6 adds the legacy scheme and setting of an
+	// onion hash to paramsV5, see legacy.go.
+	paramsV6
+)
+
+// hashVersion returns the version of the encoded hash.
+func hashVersion(buf []byte) int {
+	tmp, _, ok := consumeVarint32(buf)
+	if !ok {
+		return -1
+	}
 
-const paramsV0 = 0
+	return bits.TrailingZeros32(^tmp)
+}
+
+func appendParams(buf []byte, p *params) []byte {
+	vers := uint32(p.vers)
+	if vers == paramsV0 && p.norm != NormalizeNone {
+		panic("portunes: paramsV0 does not support normalization")
+	}
 
-func appendParams(buf []byte, time, memory uint32, threads uint8) []byte {
 	buf = appendVarint32(buf,
-		uint32(threads-1)<<(paramsV0+1)|
-			((1<<paramsV0)-1))
-	buf = appendVarint32(buf, time-1)
-	return appendVarint32(buf,
-		bits.RotateLeft32(memory, -16))
+		uint32(p.threads-1)<<(vers+1)|
+			((1<<vers)-1))
+	buf = appendVarint32(buf, p.time-1)
+	buf = appendVarint32(buf,
+		bits.RotateLeft32(p.memory, -16))
+
+	if vers != paramsV0 {
+		buf = appendVarint32(buf, uint32(p.norm))
+	}
+
+	if vers >= paramsV4 {
+		buf = appendVarint32(buf, p.saltLen)
+		buf = appendVarint32(buf, p.tagLen)
+	} else if p.saltLen != defaultSaltLen || p.tagLen != defaultTagLen {
+		panic("portunes: params version does not support salt and tag lengths")
+	}
+
+	if vers >= paramsV5 {
+		if !p.variant.valid() {
+			panic("portunes: invalid argon2 variant")
+		}
+
+		buf = appendVarint32(buf, uint32(p.variant.mode()))
+	} else if p.variant != Argon2id {
+		panic("portunes: params version does not support argon2 variants")
+	}
+
+	if vers >= paramsV6 {
+		buf = appendVarint32(buf, uint32(p.legacy))
+		buf = appendVarint32(buf, uint32(len(p.legacySetting)))
+		buf = append(buf, p.legacySetting...)
+	} else if p.legacy != legacyNone {
+		panic("portunes: params version does not support legacy hashes")
+	}
+
+	return buf
 }
 
-func consumeParams(buf []byte) (time, memory uint32, threads uint8, rest []byte) {
+func consumeParams(buf []byte) (p params, rest []byte, ok bool) {
 	tmp, buf, ok0 := consumeVarint32(buf)
 
 	vers := bits.TrailingZeros32(^tmp)
-	if vers != paramsV0 || !ok0 {
-		return 0, 0, 0, nil
+	if _, ok := formats[vers].(argon2Format); !ok0 || !ok {
+		return params{}, nil, false
 	}
 
 	time, buf, ok1 := consumeVarint32(buf)
 	memory, buf, ok2 := consumeVarint32(buf)
 
-	if !ok1 || !ok2 || tmp>>(8+paramsV0+1) != 0 {
-		return 0, 0, 0, nil
+	if !ok1 || !ok2 || tmp>>(8+vers+1) != 0 {
+		return params{}, nil, false
+	}
+
+	p.vers = uint8(vers)
+	p.time = time + 1
+	p.memory = bits.RotateLeft32(memory, 16)
+	p.threads = uint8(tmp>>(vers+1)) + 1
+
+	// Both of these wrap around to zero which argon2
+	// doesn't accept.
+	if p.time == 0 || p.threads == 0 {
+		return params{}, nil, false
 	}
 
-	time++
-	memory = bits.RotateLeft32(memory, 16)
-	threads = uint8(tmp>>(paramsV0+1)) + 1
+	if vers != paramsV0 {
+		norm, rest, ok := consumeVarint32(buf)
+		if !ok || norm > uint32(maxNormalization) ||
+			(vers == paramsV1 && norm == uint32(NormalizeNone)) {
+			return params{}, nil, false
+		}
+
+		p.norm, buf = Normalization(norm), rest
+	}
+
+	p.saltLen, p.tagLen = defaultSaltLen, defaultTagLen
+
+	if vers >= paramsV4 {
+		saltLen, rest, ok1 := consumeVarint32(buf)
+		tagLen, rest, ok2 := consumeVarint32(rest)
+		if !ok1 || !ok2 || saltLen < minSaltLen || tagLen < minTagLen {
+			return params{}, nil, false
+		}
+
+		p.saltLen, p.tagLen, buf = saltLen, tagLen, rest
+	}
+
+	p.variant = Argon2id
+
+	if vers >= paramsV5 {
+		variant, rest, ok := consumeVarint32(buf)
+		if !ok || variant > uint32(Argon2id.mode()) {
+			return params{}, nil, false
+		}
+
+		p.variant, buf = Variant(variant)+1, rest
+	}
+
+	if vers >= paramsV6 {
+		legacy, rest, ok1 := consumeVarint32(buf)
+		settingLen, rest, ok2 := consumeVarint32(rest)
+		if !ok1 || !ok2 || legacy == uint32(legacyNone) || legacy > uint32(maxLegacyScheme) ||
+			settingLen > maxLegacySetting || uint64(len(rest)) < uint64(settingLen) {
+			return params{}, nil, false
+		}
+
+		setting := rest[:settingLen]
+		if !legacyHashers[legacy].validSetting(setting) {
+			return params{}, nil, false
+		}
+
+		p.legacy, p.legacySetting = legacyScheme(legacy), string(setting)
+		buf = rest[settingLen:]
+	}
 
-	return time, memory, threads, buf
+	return p, buf, true
 }
@@ -10,11 +10,45 @@ import (
 func TestParamEncoding(t *testing.T) {
 	t.Parallel()
 
-	assert.NoError(t, quick.Check(func(time, memory uint32, threads uint8) bool {
-		buf := appendParams(nil, time, memory, threads)
-		time2, memory2, threads2, rest := consumeParams(buf)
-		return time == time2 && memory == memory2 &&
-			threads == threads2 && len(rest) == 0
+	versions := [...]uint8{paramsV0, paramsV1, paramsV3, paramsV4, paramsV5, paramsV6}
+
+	assert.NoError(t, quick.Check(func(vers uint8, time, memory uint32, threads, norm uint8, saltLen, tagLen uint32, variant uint8) bool {
+		p := params{
+			vers: versions[int(vers)%len(versions)],
+
+			time:    time%(1<<32-1) + 1,
+			memory:  memory,
+			threads: threads%(1<<8-1) + 1,
+
+			norm: Normalization(norm) % (maxNormalization + 1),
+
+			saltLen: defaultSaltLen,
+			tagLen:  defaultTagLen,
+
+			variant: Argon2id,
+		}
+
+		switch p.vers {
+		case paramsV0:
+			p.norm = NormalizeNone
+		case paramsV1:
+			if p.norm == NormalizeNone {
+				p.norm = NormalizeNFC
+			}
+		case paramsV6:
+			p.legacy, p.legacySetting = legacyBcrypt, "$2a$10$XajjQvNhvvRt5GSeFk1xFe"
+			fallthrough
+		case paramsV5:
+			p.variant = Variant(variant)%Argon2id + 1
+			fallthrough
+		case paramsV4:
+			p.saltLen = minSaltLen + saltLen%(1<<31)
+			p.tagLen = minTagLen + tagLen%(1<<31)
+		}
+
+		buf := appendParams(nil, &p)
+		p2, rest, ok := consumeParams(buf)
+		return ok && p == p2 && len(rest) == 0
 	}, &quick.Config{
 		MaxCountScale: 10000,
 	}))
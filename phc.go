@@ -0,0 +1,96 @@
+package portunes
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errInvalidPHC = errors.New("portunes: invalid argon2 PHC string")
+
+// FromPHC converts an Argon2 hash in the PHC string format,
+// as produced by the reference implementation and most
+// Argon2 libraries, into a hash that can be passed to
+// Verify. For example:
+//
+//	$argon2i$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA
+//
+// Such hashes are not peppered, so the converted hash must
+// be verified with a nil pepper. Only version 19 (0x13) of
+// Argon2 is supported.
+func FromPHC(phc string) ([]byte, error) {
+	parts := strings.Split(phc, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[2] != "v=19" {
+		return nil, errInvalidPHC
+	}
+
+	p := params{
+		norm: NormalizeNone,
+	}
+
+	switch parts[1] {
+	case "argon2d":
+		p.variant = Argon2d
+	case "argon2i":
+		p.variant = Argon2i
+	case "argon2id":
+		p.variant = Argon2id
+	default:
+		return nil, errInvalidPHC
+	}
+
+	var seen int
+	for _, param := range strings.Split(parts[3], ",") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return nil, errInvalidPHC
+		}
+
+		bitSize := 32
+		if kv[0] == "p" {
+			bitSize = 8
+		}
+
+		v, err := strconv.ParseUint(kv[1], 10, bitSize)
+		if err != nil || v == 0 {
+			return nil, errInvalidPHC
+		}
+
+		switch kv[0] {
+		case "m":
+			p.memory = uint32(v)
+			seen |= 1
+		case "t":
+			p.time = uint32(v)
+			seen |= 2
+		case "p":
+			p.threads = uint8(v)
+			seen |= 4
+		default:
+			// The keyid and data parameters aren't
+			// supported as the key and associated data
+			// aren't available.
+			return nil, errInvalidPHC
+		}
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, errInvalidPHC
+	}
+
+	tag, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, errInvalidPHC
+	}
+
+	if seen != 7 || len(salt) < minSaltLen || len(tag) < minTagLen {
+		return nil, errInvalidPHC
+	}
+
+	p.saltLen, p.tagLen = uint32(len(salt)), uint32(len(tag))
+	p.setVersion()
+
+	return encodeHash(&p, salt, tag), nil
+}
@@ -0,0 +1,188 @@
+package portunes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/trace"
+	pb "go.tmthrgd.dev/portunes/internal/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Policy describes the requirements a password must meet.
+type Policy struct {
+	// MinLength and MaxLength bound the length of the
+	// password in Unicode code points. A zero value
+	// disables the check. A password longer than MaxLength
+	// is only reported as too long, without being checked
+	// further.
+	MinLength, MaxLength int
+
+	// MinEntropy is the minimum estimated strength of the
+	// password in bits. A zero value disables the check.
+	MinEntropy float64
+
+	// Enforce causes Hash to reject passwords that violate
+	// the policy with a *PolicyError.
+	//
+	// As Hash has no user inputs to check against, the
+	// password will only be checked against the length
+	// and strength requirements and any breach filter.
+	Enforce bool
+}
+
+// PolicyReason identifies the requirement of a Policy that
+// a password violated.
+type PolicyReason int32
+
+// These are the reasons a password may violate a Policy.
+const (
+	PolicyTooShort          = PolicyReason(pb.PolicyViolation_TOO_SHORT)
+	PolicyTooLong           = PolicyReason(pb.PolicyViolation_TOO_LONG)
+	PolicyTooWeak           = PolicyReason(pb.PolicyViolation_TOO_WEAK)
+	PolicyContainsUserInput = PolicyReason(pb.PolicyViolation_CONTAINS_USER_INPUT)
+	PolicyBreached          = PolicyReason(pb.PolicyViolation_BREACHED)
+)
+
+func (r PolicyReason) String() string {
+	return pb.PolicyViolation_Reason(r).String()
+}
+
+// PolicyViolation describes a requirement of a Policy that
+// a password did not meet.
+type PolicyViolation struct {
+	Reason  PolicyReason
+	Message string
+}
+
+// PolicyError is returned from Client.Hash when the server
+// enforces a Policy that the password violates.
+type PolicyError struct {
+	Violations []PolicyViolation
+
+	status *status.Status
+}
+
+func (e *PolicyError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+
+	return "portunes: password violates policy: " + strings.Join(msgs, ", ")
+}
+
+// GRPCStatus returns the underlying grpc status so that
+// status.Code and status.FromError work as expected.
+func (e *PolicyError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+func fromPBViolations(pbv []*pb.PolicyViolation) []PolicyViolation {
+	if len(pbv) == 0 {
+		return nil
+	}
+
+	violations := make([]PolicyViolation, len(pbv))
+	for i, v := range pbv {
+		violations[i] = PolicyViolation{
+			Reason:  PolicyReason(v.Reason),
+			Message: v.Message,
+		}
+	}
+
+	return violations
+}
+
+// checkPolicy evaluates password against the server's
+// policy and breach filter.
+func (s *Server) checkPolicy(password string, userInputs []string) *pb.CheckPolicyResponse {
+	resp := new(pb.CheckPolicyResponse)
+
+	violation := func(reason pb.PolicyViolation_Reason, format string, args ...interface{}) {
+		resp.Violations = append(resp.Violations, &pb.PolicyViolation{
+			Reason:  reason,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	p := &s.policy
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violation(pb.PolicyViolation_TOO_SHORT,
+			"password must be at least %d characters", p.MinLength)
+	}
+
+	if p.MaxLength > 0 && length > p.MaxLength {
+		// The other checks are skipped as they're more
+		// expensive the longer the password is.
+		violation(pb.PolicyViolation_TOO_LONG,
+			"password must be at most %d characters", p.MaxLength)
+		return resp
+	}
+
+	tokens := userInputTokens(userInputs)
+	resp.Entropy = estimateEntropy(password, tokens)
+
+	if p.MinEntropy > 0 && resp.Entropy < p.MinEntropy {
+		violation(pb.PolicyViolation_TOO_WEAK,
+			"password is too easy to guess")
+	}
+
+	lower := toLower(password)
+	for _, token := range tokens {
+		if strings.Contains(lower, token) {
+			violation(pb.PolicyViolation_CONTAINS_USER_INPUT,
+				"password must not contain %q", token)
+			break
+		}
+	}
+
+	if s.breached != nil && s.breached.Contains(password) {
+		violation(pb.PolicyViolation_BREACHED,
+			"password is known to be breached")
+	}
+
+	return resp
+}
+
+func (s pbServer) CheckPolicy(ctx context.Context, req *pb.CheckPolicyRequest) (*pb.CheckPolicyResponse, error) {
+	_, span := s.tracer.Start(extractTraceContext(ctx), "portunes.Hasher/CheckPolicy",
+		trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	if err := s.checkLimits([]byte(req.Password), nil); err != nil {
+		return nil, spanError(span, err)
+	}
+
+	// The password is checked as Hash would check it.
+	pw, err := normalizePassword([]byte(req.Password), s.norm)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	return s.checkPolicy(string(pw), req.UserInputs), nil
+}
+
+// enforcePolicy returns an error carrying the violations if
+// the server enforces a policy that password violates.
+func (s *Server) enforcePolicy(password []byte) error {
+	if !s.policy.Enforce {
+		return nil
+	}
+
+	resp := s.checkPolicy(string(password), nil)
+	if len(resp.Violations) == 0 {
+		return nil
+	}
+
+	st, err := status.New(codes.InvalidArgument, "password violates policy").WithDetails(resp)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	return st.Err()
+}
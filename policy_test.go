@@ -0,0 +1,138 @@
+package portunes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestEstimateEntropy(t *testing.T) {
+	t.Parallel()
+
+	tokens := userInputTokens([]string{"jsmith", "john.smith@example.com"})
+
+	for _, tc := range []struct {
+		password string
+		min, max float64
+	}{
+		{"", 0, 0},
+		{"aaaaaaaaaaaa", 0, 10},
+		{"abcdefghijkl", 0, 10},
+		{"987654321", 0, 10},
+		{"qwertyuiop", 0, 10},
+		{"JSmith", 0, 10},
+		{"smith2019", 0, 25},
+		{"x7#Kp2!vQ9zL", 50, 80},
+		{"correct horse battery staple", 100, 200},
+
+		// These change length when lower cased.
+		{"İİİİİİİİ", 0, 20},
+		{"xİ7#Kp2!", 30, 80},
+		{"ȺȾȺȾ", 20, 40},
+		{"ẞẞẞẞ", 0, 20},
+		{"KKKK", 0, 20},
+	} {
+		entropy := estimateEntropy(tc.password, tokens)
+		assert.True(t, entropy >= tc.min && entropy <= tc.max,
+			"estimateEntropy(%q) = %f, expected [%f, %f]", tc.password, entropy, tc.min, tc.max)
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	t.Parallel()
+
+	c, _, stop := testingClient(WithPolicy(Policy{
+		MinLength:  8,
+		MaxLength:  16,
+		MinEntropy: 20,
+	}))
+	defer stop()
+
+	reasons := func(violations []PolicyViolation) []PolicyReason {
+		var reasons []PolicyReason
+		for _, v := range violations {
+			assert.NotEmpty(t, v.Message)
+			reasons = append(reasons, v.Reason)
+		}
+
+		return reasons
+	}
+
+	userInputs := []string{"jsmith", "john.smith@example.com"}
+
+	for _, tc := range []struct {
+		password string
+		reasons  []PolicyReason
+	}{
+		{"x7#Kp2!vQ9zL", nil},
+		{"x7#Kp2", []PolicyReason{PolicyTooShort}},
+		{"x7#Kp2!vQ9zLx7#Kp2!vQ9zL", []PolicyReason{PolicyTooLong}},
+		{"aaaaaaaaaaaa", []PolicyReason{PolicyTooWeak}},
+		{"x7#Kp2!jsmith", []PolicyReason{PolicyContainsUserInput}},
+	} {
+		violations, entropy, err := c.CheckPolicy(context.Background(), tc.password, userInputs)
+		require.NoError(t, err)
+
+		assert.Equal(t, tc.reasons, reasons(violations), "violations for %q", tc.password)
+		assert.True(t, entropy >= 0, "entropy")
+	}
+}
+
+func TestCheckPolicyLong(t *testing.T) {
+	t.Parallel()
+
+	// This took minutes when every position was compared by
+	// copying the rest of the password.
+	long := strings.Repeat("jsmith1", 100000/7)
+	start := time.Now()
+	estimateEntropy(long, userInputTokens([]string{"jsmith", "jsmith1jsmith2"}))
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second), "estimateEntropy took too long")
+
+	c, _, stop := testingClient(
+		WithPolicy(Policy{MaxLength: 64, MinEntropy: 20}),
+		WithMaxPasswordLength(1024, 0))
+	defer stop()
+
+	violations, entropy, err := c.CheckPolicy(context.Background(), long[:100], []string{"jsmith"})
+	require.NoError(t, err)
+
+	require.Len(t, violations, 1)
+	assert.Equal(t, PolicyTooLong, violations[0].Reason)
+	assert.Zero(t, entropy, "entropy")
+
+	_, _, err = c.CheckPolicy(context.Background(), long, []string{"jsmith"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+}
+
+func TestEnforcePolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, enforce := range []bool{false, true} {
+		c, _, stop := testingClient(WithPolicy(Policy{
+			MinLength: 8,
+			Enforce:   enforce,
+		}))
+		defer stop()
+
+		_, err := c.Hash(context.Background(), "short", nil)
+		if !enforce {
+			assert.NoError(t, err)
+			continue
+		}
+
+		require.Error(t, err)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+
+		var perr *PolicyError
+		require.True(t, errors.As(err, &perr), "expected *PolicyError")
+		require.Len(t, perr.Violations, 1)
+		assert.Equal(t, PolicyTooShort, perr.Violations[0].Reason)
+	}
+}
@@ -0,0 +1,221 @@
+package portunestest
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"go.tmthrgd.dev/portunes"
+	pb "go.tmthrgd.dev/portunes/internal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// fakePrefix starts every hash returned by a Fake. It is
+// chosen so that it can't be mistaken for a real hash.
+const fakePrefix = "\x00portunestest\x00"
+
+// Fake is a scriptable fake of the portunes.Hasher service.
+//
+// By default, the Hash RPC returns the cheap, deterministic
+// hash from the package-level Hash function and the Verify
+// RPC reports whether a hash matches it for the same
+// password and pepper, never requesting a rehash. The
+// CheckPolicy RPC reports no violations. The Set methods
+// change these behaviours and are safe to call while the
+// fake is in use.
+type Fake struct {
+	l *listener
+
+	mu         sync.Mutex
+	latency    time.Duration
+	err        error
+	rehash     bool
+	verifyFn   func(password string, pepper, hash []byte) (valid, rehash bool, err error)
+	violations []portunes.PolicyViolation
+	entropy    float64
+}
+
+// NewFake starts a Fake listening on an in-memory listener.
+// The caller should call Close when finished.
+func NewFake() *Fake {
+	f := new(Fake)
+	f.l = newListener(func(srv *grpc.Server) {
+		pb.RegisterHasherServer(srv, fakeServer{f})
+	})
+	return f
+}
+
+// Client returns a new portunes.Client connected to the
+// fake. It is closed when the fake is closed.
+func (f *Fake) Client(opts ...portunes.ClientOption) *portunes.Client {
+	return f.l.client(opts)
+}
+
+// Close stops the fake and closes all of its clients.
+func (f *Fake) Close() {
+	f.l.close()
+}
+
+// Hash returns the hash that a Fake will return from Hash
+// for the given password and pepper.
+func Hash(password string, pepper []byte) []byte {
+	h := sha256.New()
+
+	var tmp [binary.MaxVarintLen64]byte
+	h.Write(tmp[:binary.PutUvarint(tmp[:], uint64(len(pepper)))])
+	h.Write(pepper)
+	h.Write([]byte(password))
+
+	return h.Sum([]byte(fakePrefix))
+}
+
+// SetLatency delays every call by d, or until the call's
+// context is done.
+func (f *Fake) SetLatency(d time.Duration) {
+	f.mu.Lock()
+	f.latency = d
+	f.mu.Unlock()
+}
+
+// SetError causes every call to fail with err, which
+// should usually come from status.Error. A nil err restores
+// the default behaviour.
+func (f *Fake) SetError(err error) {
+	f.mu.Lock()
+	f.err = err
+	f.mu.Unlock()
+}
+
+// SetRehash controls whether Verify requests a rehash of
+// valid hashes.
+func (f *Fake) SetRehash(rehash bool) {
+	f.mu.Lock()
+	f.rehash = rehash
+	f.mu.Unlock()
+}
+
+// SetVerifyFunc replaces the default behaviour of Verify
+// with fn. A nil fn restores the default behaviour.
+//
+// portunes.Client.Verify never reports rehash as true for
+// an invalid password, regardless of what fn returns.
+func (f *Fake) SetVerifyFunc(fn func(password string, pepper, hash []byte) (valid, rehash bool, err error)) {
+	f.mu.Lock()
+	f.verifyFn = fn
+	f.mu.Unlock()
+}
+
+// SetPolicyResult sets the violations and entropy that
+// CheckPolicy returns.
+func (f *Fake) SetPolicyResult(violations []portunes.PolicyViolation, entropy float64) {
+	f.mu.Lock()
+	f.violations, f.entropy = violations, entropy
+	f.mu.Unlock()
+}
+
+// wait applies the latency and returns the error set on f.
+func (f *Fake) wait(ctx context.Context) error {
+	f.mu.Lock()
+	latency, err := f.latency, f.err
+	f.mu.Unlock()
+
+	if latency > 0 {
+		t := time.NewTimer(latency)
+		defer t.Stop()
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+
+	return err
+}
+
+// fakeServer implements the portunes.Hasher service for a
+// Fake, so that the handlers aren't part of its API.
+type fakeServer struct{ *Fake }
+
+func (f fakeServer) Hash(ctx context.Context, req *pb.HashRequest) (*pb.HashResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return &pb.HashResponse{
+		Hash: Hash(string(req.Password), req.Pepper),
+	}, nil
+}
+
+func (f fakeServer) Verify(ctx context.Context, req *pb.VerifyRequest) (*pb.VerifyResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	fn, rehash := f.verifyFn, f.rehash
+	f.mu.Unlock()
+
+	if fn != nil {
+		valid, rehash, err := fn(string(req.Password), req.Pepper, req.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		return &pb.VerifyResponse{Valid: valid, Rehash: rehash}, nil
+	}
+
+	expect := Hash(string(req.Password), req.Pepper)
+	valid := subtle.ConstantTimeCompare(expect, req.Hash) == 1
+
+	return &pb.VerifyResponse{
+		Valid:  valid,
+		Rehash: rehash && valid,
+	}, nil
+}
+
+func (f fakeServer) VerifyDummy(ctx context.Context, req *pb.VerifyDummyRequest) (*pb.VerifyResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return &pb.VerifyResponse{}, nil
+}
+
+// WrapLegacy returns a deterministic hash of the legacy
+// hash. The default Verify never reports a password as
+// valid for it, use SetVerifyFunc to script that.
+func (f fakeServer) WrapLegacy(ctx context.Context, req *pb.WrapLegacyRequest) (*pb.HashResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return &pb.HashResponse{
+		Hash: Hash("\x00wrapped\x00"+string(req.LegacyHash), req.Pepper),
+	}, nil
+}
+
+func (f fakeServer) CheckPolicy(ctx context.Context, req *pb.CheckPolicyRequest) (*pb.CheckPolicyResponse, error) {
+	if err := f.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resp := &pb.CheckPolicyResponse{
+		Entropy: f.entropy,
+	}
+	for _, v := range f.violations {
+		resp.Violations = append(resp.Violations, &pb.PolicyViolation{
+			Reason:  pb.PolicyViolation_Reason(v.Reason),
+			Message: v.Message,
+		})
+	}
+
+	return resp, nil
+}
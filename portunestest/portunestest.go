@@ -0,0 +1,120 @@
+// Package portunestest provides utilities for testing code
+// that uses a portunes.Client.
+//
+// NewServer runs a real portunes.Server with parameters
+// cheap enough for tests, while NewFake runs a scriptable
+// fake that can be made to return any Verify outcome,
+// error or latency.
+package portunestest
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/hydrogen18/memlistener"
+	"go.tmthrgd.dev/portunes"
+	"google.golang.org/grpc"
+)
+
+// These are the parameters used by NewServer. They are far
+// too cheap to be used outside of tests.
+const (
+	Time    = 1
+	Memory  = 64
+	Threads = 1
+)
+
+// listener serves a grpc.Server over an in-memory
+// listener and dials clients to it.
+type listener struct {
+	ln   *memlistener.MemoryListener
+	srv  *grpc.Server
+	done chan struct{}
+
+	mu    sync.Mutex
+	conns []*grpc.ClientConn
+}
+
+func newListener(register func(*grpc.Server)) *listener {
+	l := &listener{
+		ln:   memlistener.NewMemoryListener(),
+		srv:  grpc.NewServer(),
+		done: make(chan struct{}),
+	}
+	register(l.srv)
+
+	go func() {
+		defer close(l.done)
+
+		if err := l.srv.Serve(l.ln); err != nil && err != grpc.ErrServerStopped {
+			panic("portunestest: failed to serve: " + err.Error())
+		}
+	}()
+
+	return l
+}
+
+func (l *listener) client(opts []portunes.ClientOption) *portunes.Client {
+	cc, err := grpc.Dial("portunestest",
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return l.ln.Dial("portunestest", addr)
+		}),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		panic("portunestest: failed to dial: " + err.Error())
+	}
+
+	l.mu.Lock()
+	l.conns = append(l.conns, cc)
+	l.mu.Unlock()
+
+	return portunes.NewClient(cc, opts...)
+}
+
+func (l *listener) close() {
+	l.mu.Lock()
+	for _, cc := range l.conns {
+		cc.Close()
+	}
+	l.conns = nil
+	l.mu.Unlock()
+
+	l.srv.Stop()
+	l.ln.Close()
+	<-l.done
+}
+
+// Server is a portunes.Server listening on an in-memory
+// listener.
+type Server struct {
+	*portunes.Server
+
+	l *listener
+}
+
+// NewServer starts a portunes.Server using Time, Memory and
+// Threads as the parameters. The caller should call Close
+// when finished.
+//
+// opts are passed through to portunes.NewServer.
+func NewServer(opts ...portunes.ServerOption) *Server {
+	s := portunes.NewServer(Time, Memory, Threads, opts...)
+	return &Server{
+		Server: s,
+
+		l: newListener(s.Attach),
+	}
+}
+
+// Client returns a new portunes.Client connected to the
+// server. It is closed when the server is closed.
+func (s *Server) Client(opts ...portunes.ClientOption) *portunes.Client {
+	return s.l.client(opts)
+}
+
+// Close stops the server and closes all of its clients.
+func (s *Server) Close() {
+	s.l.close()
+}
@@ -0,0 +1,196 @@
+package portunestest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/portunes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer(portunes.WithNormalization(portunes.NormalizeNFC))
+	defer s.Close()
+
+	c := s.Client()
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.False(t, rehash, "rehash")
+
+	s.SetParameters(Time, 2*Memory, Threads)
+
+	valid, rehash, err = s.Client().Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.True(t, rehash, "rehash")
+}
+
+func TestServerClose(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer()
+	c := s.Client()
+	s.Close()
+
+	_, err := c.Hash(context.Background(), "password🔐🔓", nil)
+	assert.Error(t, err)
+}
+
+func TestFake(t *testing.T) {
+	t.Parallel()
+
+	f := NewFake()
+	defer f.Close()
+
+	c := f.Client()
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+	assert.Equal(t, Hash("password🔐🔓", []byte("🔑📋")), hash)
+
+	for _, tc := range []struct {
+		password, pepper string
+		valid            bool
+	}{
+		{"password🔐🔓", "🔑📋", true},
+		{"wrong🔐🔓", "🔑📋", false},
+		{"password🔐🔓", "📋🔑", false},
+	} {
+		valid, rehash, err := c.Verify(context.Background(), tc.password, []byte(tc.pepper), hash)
+		require.NoError(t, err)
+
+		assert.Equal(t, tc.valid, valid, "valid")
+		assert.False(t, rehash, "rehash")
+	}
+
+	f.SetRehash(true)
+
+	valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.True(t, rehash, "rehash")
+
+	valid, rehash, err = c.Verify(context.Background(), "wrong🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.False(t, valid, "valid")
+	assert.False(t, rehash, "rehash")
+
+	assert.NoError(t, c.VerifyDummy(context.Background(), "password🔐🔓", nil))
+}
+
+func TestFakeError(t *testing.T) {
+	t.Parallel()
+
+	f := NewFake()
+	defer f.Close()
+
+	c := f.Client()
+
+	f.SetError(status.Error(codes.Unavailable, "down for maintenance"))
+
+	_, err := c.Hash(context.Background(), "password🔐🔓", nil)
+	assert.Equal(t, codes.Unavailable, status.Code(err), "invalid gRPC status code")
+
+	_, _, err = c.Verify(context.Background(), "password🔐🔓", nil, Hash("password🔐🔓", nil))
+	assert.Equal(t, codes.Unavailable, status.Code(err), "invalid gRPC status code")
+
+	f.SetError(nil)
+
+	valid, _, err := c.Verify(context.Background(), "password🔐🔓", nil, Hash("password🔐🔓", nil))
+	require.NoError(t, err)
+	assert.True(t, valid, "valid")
+}
+
+func TestFakeLatency(t *testing.T) {
+	t.Parallel()
+
+	f := NewFake()
+	defer f.Close()
+
+	c := f.Client()
+
+	f.SetLatency(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Hash(ctx, "password🔐🔓", nil)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err), "invalid gRPC status code")
+
+	f.SetLatency(10 * time.Millisecond)
+
+	start := time.Now()
+	_, err = c.Hash(context.Background(), "password🔐🔓", nil)
+	require.NoError(t, err)
+	assert.True(t, time.Since(start) >= 10*time.Millisecond, "latency")
+}
+
+func TestFakeVerifyFunc(t *testing.T) {
+	t.Parallel()
+
+	f := NewFake()
+	defer f.Close()
+
+	c := f.Client()
+
+	f.SetVerifyFunc(func(password string, pepper, hash []byte) (valid, rehash bool, err error) {
+		return string(hash) == "hash", true, nil
+	})
+
+	valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", nil, []byte("hash"))
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.True(t, rehash, "rehash")
+
+	valid, rehash, err = c.Verify(context.Background(), "password🔐🔓", nil, []byte("other"))
+	require.NoError(t, err)
+
+	assert.False(t, valid, "valid")
+	assert.False(t, rehash, "rehash")
+
+	f.SetVerifyFunc(func(password string, pepper, hash []byte) (valid, rehash bool, err error) {
+		return false, false, status.Error(codes.InvalidArgument, "invalid hash")
+	})
+
+	_, _, err = c.Verify(context.Background(), "password🔐🔓", nil, []byte("hash"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+}
+
+func TestFakePolicy(t *testing.T) {
+	t.Parallel()
+
+	f := NewFake()
+	defer f.Close()
+
+	c := f.Client()
+
+	violations, _, err := c.CheckPolicy(context.Background(), "password🔐🔓", nil)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	expect := []portunes.PolicyViolation{
+		{Reason: portunes.PolicyTooShort, Message: "too short"},
+	}
+	f.SetPolicyResult(expect, 12.5)
+
+	violations, entropy, err := c.CheckPolicy(context.Background(), "password🔐🔓", nil)
+	require.NoError(t, err)
+	assert.Equal(t, expect, violations)
+	assert.Equal(t, 12.5, entropy)
+}
@@ -2,32 +2,50 @@ package portunes
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/subtle"
+	"crypto/sha1"
 	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"go.tmthrgd.dev/portunes/breach"
+	"go.tmthrgd.dev/portunes/internal/argon2"
 	pb "go.tmthrgd.dev/portunes/internal/proto"
-	"golang.org/x/crypto/argon2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-const (
-	saltLen = 16
-	tagLen  = 16
-)
-
-type params struct {
-	time, memory uint32
-	threads      uint8
-}
-
 // Server represents a portunes.Hasher service.
 type Server struct {
 	params atomic.Value // *params
 
 	rehash, dosProt func(ctx context.Context, time, memory uint32, threads uint8) bool
+
+	rateLimit func(ctx context.Context) time.Duration
+
+	tracer trace.Tracer
+
+	norm Normalization
+
+	breached *breach.Filter
+
+	policy Policy
+
+	dummySalt, dummyTag []byte
+
+	keys *keyring
+
+	saltLen, tagLen uint32
+
+	variant Variant
+
+	limits limits
+
+	hardened bool
+
+	memory *memoryBudget
 }
 
 // NewServer creates a Server with the given paramaters.
@@ -37,11 +55,16 @@ func NewServer(time, memory uint32, threads uint8, opts ...ServerOption) *Server
 	s := new(Server)
 	s.SetParameters(time, memory, threads)
 	s.rehash = s.defaultRehash
+	s.tracer = otel.Tracer(instrumentationName)
+	s.saltLen, s.tagLen = defaultSaltLen, defaultTagLen
+	s.variant = Argon2id
 
 	for _, opt := range opts {
 		opt(s)
 	}
 
+	s.initDummy()
+
 	return s
 }
 
@@ -71,7 +94,46 @@ func (s *Server) SetParameters(time, memory uint32, threads uint8) {
 		panic("portunes: invalid argon2 paramaters")
 	}
 
-	s.params.Store(&params{time, memory, threads})
+	s.params.Store(&params{time: time, memory: memory, threads: threads})
+}
+
+// hashParams returns the params used for new hashes.
+func (s *Server) hashParams() params {
+	p := *s.params.Load().(*params)
+	p.norm = s.norm
+	p.saltLen, p.tagLen = s.saltLen, s.tagLen
+	p.variant = s.variant
+	p.setVersion()
+	return p
+}
+
+// outdated reports whether a hash with the given params
+// should be rehashed because it doesn't match the params
+// the server would use for a new hash.
+func (s *Server) outdated(p *params) bool {
+	return p.vers < paramsV3 ||
+		p.norm != s.norm ||
+		p.saltLen != s.saltLen || p.tagLen != s.tagLen ||
+		p.variant != s.variant ||
+		p.legacy != legacyNone
+}
+
+// deriver returns the deriver used to derive tags with mem,
+// which is nil unless WithMemoryBudget was used.
+func (s *Server) deriver(mem *argon2.Memory) deriver {
+	return deriver{tracer: s.tracer, wipe: s.hardened, mem: mem}
+}
+
+// checkPassword returns an error if password isn't valid
+// UTF-8. It was previously a string field, which the
+// protobuf runtime validated, and remains a string in the
+// client API.
+func checkPassword(password []byte) error {
+	if !utf8.Valid(password) {
+		return status.Error(codes.InvalidArgument, "invalid password: not valid UTF-8")
+	}
+
+	return nil
 }
 
 func (s *Server) defaultRehash(ctx context.Context, time, memory uint32, threads uint8) bool {
@@ -88,50 +150,153 @@ func (s *Server) Attach(srv *grpc.Server) {
 }
 
 func (s pbServer) Hash(ctx context.Context, req *pb.HashRequest) (*pb.HashResponse, error) {
-	salt := make([]byte, saltLen, saltLen+len(req.Pepper))
-	if _, err := rand.Read(salt); err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+	ctx, span := s.tracer.Start(extractTraceContext(ctx), "portunes.Hasher/Hash",
+		trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	if s.hardened {
+		defer wipe(req.Password)
+		defer wipe(req.Pepper)
 	}
 
-	p := s.params.Load().(*params)
+	if err := checkPassword(req.Password); err != nil {
+		return nil, spanError(span, err)
+	}
 
-	hash := argon2.IDKey(
-		[]byte(req.Password),
-		append(salt, req.Pepper...),
-		p.time, p.memory, p.threads, tagLen)
+	if err := s.checkLimits(req.Password, req.Pepper); err != nil {
+		return nil, spanError(span, err)
+	}
 
-	res := make([]byte, 0, maxParamsLength+len(salt)+len(hash))
-	res = appendParams(res, p.time, p.memory, p.threads)
-	res = append(res, salt...)
-	res = append(res, hash...)
+	p := s.hashParams()
+
+	// The breach and policy checks are applied to the
+	// normalized password, as that's what gets hashed.
+	pw, err := normalizePassword(req.Password, p.norm)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	if s.hardened {
+		defer wipe(pw)
+	}
+
+	if s.breached != nil && s.breached.ContainsSHA1(sha1.Sum(pw)) {
+		return nil, spanError(span, status.Error(codes.InvalidArgument, "password is known to be breached"))
+	}
+
+	if err := s.enforcePolicy(pw); err != nil {
+		return nil, spanError(span, err)
+	}
+
+	salt, err := newSalt(&p)
+	if err != nil {
+		return nil, spanError(span, status.Error(codes.Internal, err.Error()))
+	}
+
+	mem, err := s.admit(ctx, &p, nil)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer s.release(mem)
+
+	tag, err := s.deriver(mem).deriveNormalizedKey(ctx, pw, req.Pepper, salt, &p)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	hash := encodeHash(&p, salt, tag)
+
+	if s.hardened {
+		wipe(tag)
+	}
+
+	hash, err = s.seal(hash)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
 
 	return &pb.HashResponse{
-		Hash: res,
+		Hash: hash,
 	}, nil
 }
 
+// seal encrypts hash with the primary envelope key, if
+// any. With WithHardenedMemory, the unencrypted hash is
+// zeroed.
+func (s *Server) seal(hash []byte) ([]byte, error) {
+	if s.keys == nil {
+		return hash, nil
+	}
+
+	if s.hardened {
+		defer wipe(hash)
+	}
+
+	sealed, err := s.keys.seal(hash)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return sealed, nil
+}
+
 func (s pbServer) Verify(ctx context.Context, req *pb.VerifyRequest) (*pb.VerifyResponse, error) {
-	time, memory, threads, hash := consumeParams(req.Hash)
-	if len(hash) != saltLen+tagLen {
-		return nil, status.Error(codes.InvalidArgument, "invalid hash")
+	ctx, span := s.tracer.Start(extractTraceContext(ctx), "portunes.Hasher/Verify",
+		trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	if s.hardened {
+		defer wipe(req.Password)
+		defer wipe(req.Pepper)
 	}
 
-	if s.dosProt != nil && !s.dosProt(ctx, time, memory, threads) {
-		return nil, status.Error(codes.ResourceExhausted, "dos protection callback refused")
+	if err := checkPassword(req.Password); err != nil {
+		return nil, spanError(span, err)
 	}
 
-	salt, hash := hash[:saltLen:saltLen], hash[saltLen:]
+	if err := s.checkLimits(req.Password, req.Pepper); err != nil {
+		return nil, spanError(span, err)
+	}
+
+	d, err := decodeHash(s.keys, req.Hash)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	// The tag of an encrypted hash is only ever held in
+	// memory by the server.
+	if s.hardened && d.sealed {
+		defer wipe(d.tag)
+	}
 
-	expect := argon2.IDKey(
-		[]byte(req.Password),
-		append(salt, req.Pepper...),
-		time, memory, threads, tagLen)
+	p := &d.params
+	mem, err := s.admit(ctx, p, s.dosProt)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer s.release(mem)
 
-	valid := subtle.ConstantTimeCompare(expect, hash) == 1
+	valid, err := d.format.verify(ctx, s.deriver(mem), d, req.Password, req.Pepper)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
 
 	// Always call s.rehash regardless of password
 	// validity to limit a potential side-channel leak.
-	rehash := s.rehash != nil && s.rehash(ctx, time, memory, threads)
+	//
+	// A nil s.rehash means never rehash.
+	var rehash bool
+	if s.rehash != nil {
+		rehash = s.rehash(ctx, p.time, p.memory, p.threads)
+		rehash = rehash || s.outdated(p)
+
+		// Rehash if the hash isn't encrypted with the
+		// current primary key, including if it isn't
+		// encrypted at all.
+		if s.keys != nil {
+			rehash = rehash || !d.sealed || d.keyID != s.keys.primary
+		}
+	}
 
 	return &pb.VerifyResponse{
 		Valid: valid,
@@ -149,6 +314,52 @@ func (s pbServer) Verify(ctx context.Context, req *pb.VerifyRequest) (*pb.Verify
 	}, nil
 }
 
+// admit calls the rate limit callback, if any, and dosProt,
+// if it isn't nil, and then waits for argon2 memory from the
+// memory budget, if any. It records the time spent waiting
+// on all of them. The callbacks are where any other
+// admission control or queueing happens.
+//
+// The returned memory, which is nil without a budget, must
+// be given to release once the request is done with it.
+func (s *Server) admit(ctx context.Context, p *params, dosProt func(ctx context.Context, time, memory uint32, threads uint8) bool) (*argon2.Memory, error) {
+	if s.rateLimit == nil && dosProt == nil && s.memory == nil {
+		return nil, nil
+	}
+
+	ctx, span := s.tracer.Start(ctx, "portunes.admission",
+		trace.WithAttributes(paramsAttributes(p.time, p.memory, p.threads)...))
+	defer span.End()
+
+	if s.rateLimit != nil {
+		if retryDelay := s.rateLimit(ctx); retryDelay > 0 {
+			return nil, errRateLimited(retryDelay)
+		}
+	}
+
+	if dosProt != nil && !dosProt(ctx, p.time, p.memory, p.threads) {
+		return nil, errCostTooHigh("dos protection callback refused", p)
+	}
+
+	if s.memory == nil {
+		return nil, nil
+	}
+
+	blocks := argon2.Blocks(p.memory, p.threads)
+	if !s.memory.fits(blocks) {
+		return nil, errCostTooHigh("memory cost exceeds the memory budget", p)
+	}
+
+	return s.memory.get(ctx, blocks)
+}
+
+// release returns memory from admit to the memory budget.
+func (s *Server) release(mem *argon2.Memory) {
+	if mem != nil {
+		s.memory.put(mem)
+	}
+}
+
 // ServerOption allows changing the behaviour of the server.
 type ServerOption func(*Server)
 
@@ -156,6 +367,11 @@ type ServerOption func(*Server)
 // if a password should be rehashed or not. If fn is nil,
 // the rehash result will always be false.
 //
+// Otherwise, the rehash result will also be true if the
+// hash was created with an older format, a different
+// normalization, salt or tag length, argon2 variant or
+// envelope key, or if it wraps a legacy hash.
+//
 // By default, rehash will be true if the memory usage has
 // increased.
 func WithRehashFunc(fn func(ctx context.Context, time, memory uint32, threads uint8) bool) ServerOption {
@@ -169,9 +385,189 @@ func WithRehashFunc(fn func(ctx context.Context, time, memory uint32, threads ui
 // a work cost.
 //
 // The callback should return false to reject the the hash.
-// By default all password verification will be accepted.
+// Rejected requests fail with codes.ResourceExhausted and a
+// COST_TOO_HIGH reason, which Client returns as an *Error
+// matching ErrCostTooHigh. By default all password
+// verification will be accepted.
 func WithDOSProtectionFunc(fn func(ctx context.Context, time, memory uint32, threads uint8) bool) ServerOption {
 	return func(s *Server) {
 		s.dosProt = fn
 	}
 }
+
+// WithRateLimitFunc allows setting a callback to refuse
+// requests to Hash, Verify, VerifyDummy and WrapLegacy
+// before any argon2 work is done, such as when a client has
+// made too many requests. The callback should return how
+// long the client should wait before retrying to refuse the
+// request, or zero to allow it.
+//
+// Refused requests fail with codes.ResourceExhausted and a
+// RATE_LIMITED reason, which Client returns as an *Error
+// matching ErrRateLimited, along with the retry delay.
+//
+// The callback is called before any WithDOSProtectionFunc
+// callback. By default no requests are refused.
+func WithRateLimitFunc(fn func(ctx context.Context) (retryDelay time.Duration)) ServerOption {
+	return func(s *Server) {
+		s.rateLimit = fn
+	}
+}
+
+// WithTracerProvider sets the OpenTelemetry TracerProvider
+// used to record spans for each request.
+//
+// By default the global TracerProvider is used. Incoming
+// trace context is extracted from the request metadata
+// with the global TextMapPropagator.
+func WithTracerProvider(tp trace.TracerProvider) ServerOption {
+	return func(s *Server) {
+		s.tracer = tp.Tracer(instrumentationName)
+	}
+}
+
+// WithNormalization sets the normalization applied to
+// passwords before they are hashed. Hashes record the
+// normalization used, so changing it will not affect the
+// verification of existing hashes. Passwords verified
+// against a hash with a different normalization will be
+// marked for rehashing.
+//
+// By default passwords are not normalized.
+func WithNormalization(n Normalization) ServerOption {
+	if n > maxNormalization {
+		panic("portunes: invalid normalization")
+	}
+
+	return func(s *Server) {
+		s.norm = n
+	}
+}
+
+// WithBreachFilter causes Hash to reject passwords that are
+// found in the given breach.Filter with codes.InvalidArgument.
+// Verify is unaffected.
+//
+// As the filter is probabilistic, a small fraction of
+// passwords that have not been breached will also be
+// rejected.
+func WithBreachFilter(f *breach.Filter) ServerOption {
+	return func(s *Server) {
+		s.breached = f
+	}
+}
+
+// WithPolicy sets the Policy that CheckPolicy evaluates
+// passwords against. If p.Enforce is true, Hash will also
+// reject passwords that violate the policy.
+//
+// By default CheckPolicy only checks for user inputs and
+// against any breach filter.
+func WithPolicy(p Policy) ServerOption {
+	return func(s *Server) {
+		s.policy = p
+	}
+}
+
+// WithEnvelopeKeys causes the server to encrypt every hash
+// it produces with the primary key so that hashes stolen
+// from a database are useless without the server's keys.
+//
+// Verify accepts hashes encrypted with any of the keys,
+// as well as unencrypted hashes, and marks them for
+// rehashing unless they were encrypted with the primary
+// key. To rotate keys, add a new key and make it the
+// primary, then remove the old key once hashes have been
+// migrated.
+func WithEnvelopeKeys(primary uint32, keys ...EnvelopeKey) ServerOption {
+	kr := newKeyring(primary, keys)
+	return func(s *Server) {
+		s.keys = kr
+	}
+}
+
+// WithHashLengths sets the length in bytes of the random
+// salt and the derived tag for new hashes. Hashes record
+// the lengths used, so changing them will not affect the
+// verification of existing hashes. Passwords verified
+// against a hash with different lengths will be marked for
+// rehashing.
+//
+// The salt must be at least 8 bytes and the tag at least 4
+// bytes. By default both are 16 bytes.
+func WithHashLengths(saltLen, tagLen uint32) ServerOption {
+	if saltLen < minSaltLen || tagLen < minTagLen {
+		panic("portunes: invalid salt or tag length")
+	}
+
+	return func(s *Server) {
+		s.saltLen, s.tagLen = saltLen, tagLen
+	}
+}
+
+// WithVariant sets the Argon2 variant used for new hashes.
+// Hashes record the variant used, so Verify supports all
+// variants regardless. Passwords verified against a hash
+// with a different variant will be marked for rehashing.
+//
+// By default Argon2id is used and it should only be changed
+// for compatibility with other systems.
+func WithVariant(v Variant) ServerOption {
+	if !v.valid() {
+		panic("portunes: invalid argon2 variant")
+	}
+
+	return func(s *Server) {
+		s.variant = v
+	}
+}
+
+// WithHardenedMemory causes the server to zero passwords,
+// peppers and derived tags, along with the intermediate
+// buffers derived from them and the memory used by argon2,
+// once it's done with them. This narrows the window in
+// which they could be recovered from a memory or core dump,
+// at the cost of an extra pass over the argon2 memory.
+//
+// The password and pepper fields of requests are zeroed
+// when the handler returns, so they can't be inspected by
+// any grpc.UnaryServerInterceptor after that.
+//
+// This is a best effort. The buffers grpc decodes requests
+// from, the internal state of the hash functions and, if a
+// Policy is enforced, the copy of the password it checks
+// are not covered. See LockEnvelopeKeys to protect the keys
+// given to WithEnvelopeKeys.
+func WithHardenedMemory() ServerOption {
+	return func(s *Server) {
+		s.hardened = true
+	}
+}
+
+// WithMemoryBudget bounds the memory used by argon2 across
+// all concurrent requests to kib KiB. Requests wait for
+// memory, once admitted by any WithDOSProtectionFunc
+// callback, until it's available or their context is done.
+// A request whose hash needs more than kib KiB on its own
+// fails with codes.ResourceExhausted and a COST_TOO_HIGH
+// reason, as does every Hash if the server's parameters do.
+//
+// The memory is kept and reused by later requests for the
+// same amount of memory, rather than left to the garbage
+// collector, which reduces allocations and the latency of
+// garbage collection under load. Idle memory is freed when
+// a request for a different amount needs the room. With
+// WithHardenedMemory, the memory is zeroed before it's
+// reused.
+//
+// By default the memory is allocated for each request and
+// isn't bounded.
+func WithMemoryBudget(kib uint64) ServerOption {
+	if kib == 0 {
+		panic("portunes: invalid memory budget")
+	}
+
+	return func(s *Server) {
+		s.memory = newMemoryBudget(kib)
+	}
+}
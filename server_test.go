@@ -4,7 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"golang.org/x/crypto/argon2"
+	"go.tmthrgd.dev/portunes/internal/argon2"
 )
 
 func TestArgon2Version(t *testing.T) {
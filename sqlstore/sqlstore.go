@@ -0,0 +1,135 @@
+// Package sqlstore implements portunes.UserStore on top of
+// a database/sql table.
+//
+// Upgraded hashes are written with a compare-and-swap
+// UPDATE so that a hash changed concurrently, such as by a
+// password reset, is never overwritten by a rehash of the
+// old password.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strconv"
+
+	"go.tmthrgd.dev/portunes"
+)
+
+// ErrHashChanged is returned from UpdateHash when the
+// stored hash no longer matches the old hash.
+var ErrHashChanged = errors.New("sqlstore: hash changed concurrently")
+
+var identRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Store is a portunes.UserStore backed by a database/sql
+// table with a user column and a hash column.
+type Store struct {
+	db *sql.DB
+
+	lookup, update string
+}
+
+type config struct {
+	placeholder func(n int) string
+}
+
+// Option allows changing the behaviour of a Store.
+type Option func(*config)
+
+// WithDollarPlaceholders uses PostgreSQL style $1, $2, ...
+// placeholders in queries instead of ?.
+func WithDollarPlaceholders() Option {
+	return func(c *config) {
+		c.placeholder = func(n int) string {
+			return "$" + strconv.Itoa(n)
+		}
+	}
+}
+
+// New returns a Store for the given table and column names.
+// The hash column must be able to hold arbitrary bytes,
+// such as a BLOB or bytea column.
+//
+// The names are included in queries verbatim, so New panics
+// if they are not simple identifiers.
+func New(db *sql.DB, table, userColumn, hashColumn string, opts ...Option) *Store {
+	for _, name := range []string{table, userColumn, hashColumn} {
+		if !identRegexp.MatchString(name) {
+			panic("sqlstore: invalid identifier " + strconv.Quote(name))
+		}
+	}
+
+	cfg := &config{
+		placeholder: func(int) string {
+			return "?"
+		},
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	p := cfg.placeholder
+	return &Store{
+		db: db,
+
+		lookup: "SELECT " + hashColumn + " FROM " + table +
+			" WHERE " + userColumn + " = " + p(1),
+		update: "UPDATE " + table + " SET " + hashColumn + " = " + p(1) +
+			" WHERE " + userColumn + " = " + p(2) + " AND " + hashColumn + " = " + p(3),
+	}
+}
+
+// LookupHash implements portunes.UserStore.
+func (s *Store) LookupHash(ctx context.Context, user string) ([]byte, error) {
+	var hash []byte
+	err := s.db.QueryRowContext(ctx, s.lookup, user).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return nil, portunes.ErrUnknownUser
+	}
+
+	return hash, err
+}
+
+// UpdateHash replaces the hash for user with newHash, but
+// only if it is still oldHash. Otherwise it returns
+// ErrHashChanged.
+//
+// It has the signature of a portunes.RehashFunc.
+func (s *Store) UpdateHash(ctx context.Context, user string, oldHash, newHash []byte) error {
+	res, err := s.db.ExecContext(ctx, s.update, newHash, user, oldHash)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrHashChanged
+	}
+
+	return nil
+}
+
+// Authenticate verifies password for user with
+// c.Authenticate, replacing the stored hash with
+// UpdateHash if the server advises a rehash.
+//
+// A hash that changed concurrently is left alone and isn't
+// treated as an error.
+func (s *Store) Authenticate(ctx context.Context, c *portunes.Client, user, password string, pepper []byte) (valid bool, err error) {
+	return c.Authenticate(ctx, s, user, password, pepper, s.rehash)
+}
+
+func (s *Store) rehash(ctx context.Context, user string, oldHash, newHash []byte) error {
+	if err := s.UpdateHash(ctx, user, oldHash, newHash); err != ErrHashChanged {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package sqlstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/portunes"
+	"go.tmthrgd.dev/portunes/portunestest"
+)
+
+// fakeDB is an in-memory database/sql driver that only
+// understands the two queries issued by Store. Each
+// connection shares the same table.
+type fakeDB struct {
+	mu      sync.Mutex
+	hashes  map[string][]byte
+	queries []string
+
+	// beforeUpdate is called before an UPDATE is applied
+	// to simulate concurrent writers.
+	beforeUpdate func()
+}
+
+func (db *fakeDB) Open(string) (driver.Conn, error) { return fakeConn{db}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{c.db, query}, nil
+}
+
+func (fakeConn) Close() error { return nil }
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fakeDB: transactions not supported")
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if !strings.HasPrefix(s.query, "UPDATE ") {
+		return nil, errors.New("fakeDB: unsupported query")
+	}
+
+	if s.db.beforeUpdate != nil {
+		s.db.beforeUpdate()
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.queries = append(s.db.queries, s.query)
+
+	newHash, user, oldHash := args[0].([]byte), args[1].(string), args[2].([]byte)
+	if hash, ok := s.db.hashes[user]; !ok || !bytes.Equal(hash, oldHash) {
+		return driver.RowsAffected(0), nil
+	}
+
+	s.db.hashes[user] = append([]byte(nil), newHash...)
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.HasPrefix(s.query, "SELECT ") {
+		return nil, errors.New("fakeDB: unsupported query")
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.queries = append(s.db.queries, s.query)
+
+	hash, ok := s.db.hashes[args[0].(string)]
+	if !ok {
+		return &fakeRows{}, nil
+	}
+
+	return &fakeRows{hash: append([]byte(nil), hash...), ok: true}, nil
+}
+
+type fakeRows struct {
+	hash []byte
+	ok   bool
+}
+
+func (*fakeRows) Columns() []string { return []string{"hash"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if !r.ok {
+		return io.EOF
+	}
+
+	dest[0], r.ok = r.hash, false
+	return nil
+}
+
+// testingDB returns a *sql.DB backed by a new fakeDB.
+func testingDB(hashes map[string][]byte) (*sql.DB, *fakeDB) {
+	fdb := &fakeDB{hashes: hashes}
+	return sql.OpenDB(fakeConnector{fdb}), fdb
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{c.db}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return c.db }
+
+func TestQueries(t *testing.T) {
+	t.Parallel()
+
+	db, fdb := testingDB(map[string][]byte{"alice": []byte("hash")})
+	defer db.Close()
+
+	s := New(db, "users", "name", "password_hash")
+
+	_, err := s.LookupHash(context.Background(), "alice")
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateHash(context.Background(), "alice", []byte("hash"), []byte("new")))
+
+	s = New(db, "auth.users", "name", "password_hash", WithDollarPlaceholders())
+
+	_, err = s.LookupHash(context.Background(), "alice")
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateHash(context.Background(), "alice", []byte("new"), []byte("newer")))
+
+	assert.Equal(t, []string{
+		"SELECT password_hash FROM users WHERE name = ?",
+		"UPDATE users SET password_hash = ? WHERE name = ? AND password_hash = ?",
+		"SELECT password_hash FROM auth.users WHERE name = $1",
+		"UPDATE auth.users SET password_hash = $1 WHERE name = $2 AND password_hash = $3",
+	}, fdb.queries)
+
+	for _, name := range []string{"", "users; DROP TABLE users", "a.b.c", "1users", `"users"`} {
+		assert.Panics(t, func() {
+			New(db, name, "name", "password_hash")
+		}, name)
+	}
+}
+
+func TestLookupHash(t *testing.T) {
+	t.Parallel()
+
+	db, _ := testingDB(map[string][]byte{"alice": []byte("hash")})
+	defer db.Close()
+
+	s := New(db, "users", "name", "password_hash")
+
+	hash, err := s.LookupHash(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hash"), hash)
+
+	_, err = s.LookupHash(context.Background(), "mallory")
+	assert.True(t, errors.Is(err, portunes.ErrUnknownUser), "ErrUnknownUser")
+}
+
+func TestUpdateHash(t *testing.T) {
+	t.Parallel()
+
+	db, fdb := testingDB(map[string][]byte{"alice": []byte("hash")})
+	defer db.Close()
+
+	s := New(db, "users", "name", "password_hash")
+
+	assert.Equal(t, ErrHashChanged, s.UpdateHash(context.Background(), "alice", []byte("other"), []byte("new")))
+	assert.Equal(t, []byte("hash"), fdb.hashes["alice"])
+
+	assert.NoError(t, s.UpdateHash(context.Background(), "alice", []byte("hash"), []byte("new")))
+	assert.Equal(t, []byte("new"), fdb.hashes["alice"])
+
+	assert.Equal(t, ErrHashChanged, s.UpdateHash(context.Background(), "mallory", []byte("hash"), []byte("new")))
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	srv := portunestest.NewServer()
+	defer srv.Close()
+
+	c := srv.Client()
+
+	srv.SetParameters(portunestest.Time, portunestest.Memory/2, portunestest.Threads)
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", nil)
+	require.NoError(t, err)
+
+	srv.SetParameters(portunestest.Time, portunestest.Memory, portunestest.Threads)
+
+	db, fdb := testingDB(map[string][]byte{"alice": hash})
+	defer db.Close()
+
+	s := New(db, "users", "name", "password_hash")
+
+	valid, err := s.Authenticate(context.Background(), c, "alice", "wrong", nil)
+	require.NoError(t, err)
+	assert.False(t, valid, "valid")
+	assert.Equal(t, hash, fdb.hashes["alice"], "rehashed invalid password")
+
+	valid, err = s.Authenticate(context.Background(), c, "mallory", "password🔐🔓", nil)
+	require.NoError(t, err)
+	assert.False(t, valid, "valid")
+
+	// A concurrent password change must not be clobbered.
+	fdb.beforeUpdate = func() {
+		fdb.mu.Lock()
+		fdb.hashes["alice"] = []byte("reset")
+		fdb.mu.Unlock()
+	}
+
+	valid, err = s.Authenticate(context.Background(), c, "alice", "password🔐🔓", nil)
+	require.NoError(t, err)
+	assert.True(t, valid, "valid")
+	assert.Equal(t, []byte("reset"), fdb.hashes["alice"], "concurrent change overwritten")
+
+	fdb.beforeUpdate = nil
+	fdb.hashes["alice"] = hash
+
+	valid, err = s.Authenticate(context.Background(), c, "alice", "password🔐🔓", nil)
+	require.NoError(t, err)
+	assert.True(t, valid, "valid")
+	assert.NotEqual(t, hash, fdb.hashes["alice"], "not rehashed")
+
+	valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", nil, fdb.hashes["alice"])
+	require.NoError(t, err)
+	assert.True(t, valid, "new hash valid")
+	assert.False(t, rehash, "new hash rehash")
+}
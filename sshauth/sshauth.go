@@ -0,0 +1,137 @@
+// Package sshauth adapts a portunes.Client for password
+// authentication in golang.org/x/crypto/ssh servers.
+package sshauth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.tmthrgd.dev/portunes"
+	"go.tmthrgd.dev/portunes/throttle"
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrInvalidPassword is returned from the PasswordCallback
+// when the user doesn't exist or the password is wrong.
+var ErrInvalidPassword = errors.New("sshauth: invalid password")
+
+type config struct {
+	pepper   []byte
+	rehash   portunes.RehashFunc
+	throttle *throttle.Throttle
+	timeout  time.Duration
+	perms    func(user string) *ssh.Permissions
+	errorFn  func(conn ssh.ConnMetadata, err error)
+}
+
+// Option allows changing the behaviour of PasswordCallback.
+type Option func(*config)
+
+// WithPepper sets the pepper passed to Client.Verify.
+func WithPepper(pepper []byte) Option {
+	return func(c *config) {
+		c.pepper = pepper
+	}
+}
+
+// WithRehashFunc sets the function called to persist a new
+// hash when the server advises that a user's hash should be
+// rehashed. Without it, hashes are never upgraded.
+func WithRehashFunc(fn portunes.RehashFunc) Option {
+	return func(c *config) {
+		c.rehash = fn
+	}
+}
+
+// WithThrottle delays password attempts for users with
+// recent failures using t. Failures are recorded for
+// unknown users too, so as not to reveal which exist.
+func WithThrottle(t *throttle.Throttle) Option {
+	return func(c *config) {
+		c.throttle = t
+	}
+}
+
+// WithTimeout bounds the time spent on each password
+// attempt, including any throttling delay. It defaults to
+// 30 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithPermissions sets a function that returns the
+// ssh.Permissions for an authenticated user.
+func WithPermissions(fn func(user string) *ssh.Permissions) Option {
+	return func(c *config) {
+		c.perms = fn
+	}
+}
+
+// WithErrorFunc sets the function called with any error
+// that occurs while authenticating a user, other than an
+// invalid password. By default errors are logged with the
+// log package.
+func WithErrorFunc(fn func(conn ssh.ConnMetadata, err error)) Option {
+	return func(c *config) {
+		c.errorFn = fn
+	}
+}
+
+// PasswordCallback returns a function for use as the
+// PasswordCallback of an ssh.ServerConfig. It verifies
+// passwords for users in store with c.Authenticate.
+func PasswordCallback(c *portunes.Client, store portunes.UserStore, opts ...Option) func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	cfg := &config{
+		timeout: 30 * time.Second,
+		perms: func(string) *ssh.Permissions {
+			return nil
+		},
+		errorFn: func(conn ssh.ConnMetadata, err error) {
+			log.Printf("sshauth: failed to authenticate %q from %s: %v", conn.User(), conn.RemoteAddr(), err)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+		defer cancel()
+
+		user := conn.User()
+
+		if cfg.throttle != nil {
+			if err := cfg.throttle.Wait(ctx, user); err != nil {
+				return nil, err
+			}
+		}
+
+		valid, err := c.Authenticate(ctx, store, user, string(password), cfg.pepper, cfg.rehash)
+		if err != nil {
+			cfg.errorFn(conn, err)
+		}
+
+		if !valid {
+			if err != nil {
+				return nil, err
+			}
+
+			if cfg.throttle != nil {
+				cfg.throttle.Failure(user)
+			}
+
+			return nil, ErrInvalidPassword
+		}
+
+		if cfg.throttle != nil {
+			cfg.throttle.Success(user)
+		}
+
+		return cfg.perms(user), nil
+	}
+}
@@ -0,0 +1,191 @@
+package sshauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/portunes"
+	"go.tmthrgd.dev/portunes/portunestest"
+	"go.tmthrgd.dev/portunes/throttle"
+	"golang.org/x/crypto/ssh"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type mapStore map[string][]byte
+
+func (s mapStore) LookupHash(ctx context.Context, user string) ([]byte, error) {
+	hash, ok := s[user]
+	if !ok {
+		return nil, portunes.ErrUnknownUser
+	}
+
+	return hash, nil
+}
+
+type connMetadata string
+
+func (c connMetadata) User() string        { return string(c) }
+func (connMetadata) SessionID() []byte     { return nil }
+func (connMetadata) ClientVersion() []byte { return nil }
+func (connMetadata) ServerVersion() []byte { return nil }
+func (connMetadata) RemoteAddr() net.Addr  { return &net.TCPAddr{} }
+func (connMetadata) LocalAddr() net.Addr   { return &net.TCPAddr{} }
+
+// handshake runs an SSH handshake over a loopback
+// connection and returns the error from the client.
+func handshake(t *testing.T, config *ssh.ServerConfig, user, password string) error {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		sc, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer sc.Close()
+
+		conn, chans, reqs, err := ssh.NewServerConn(sc, config)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		go ssh.DiscardRequests(reqs)
+		for ch := range chans {
+			ch.Reject(ssh.Prohibited, "")
+		}
+	}()
+
+	conn, err := ssh.Dial("tcp", ln.Addr().String(), &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.Password(password)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+func TestPasswordCallback(t *testing.T) {
+	t.Parallel()
+
+	s := portunestest.NewServer()
+	defer s.Close()
+
+	c := s.Client()
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	store := mapStore{"alice": hash}
+	config := &ssh.ServerConfig{
+		PasswordCallback: PasswordCallback(c, store,
+			WithPepper([]byte("🔑📋")),
+			WithPermissions(func(user string) *ssh.Permissions {
+				return &ssh.Permissions{Extensions: map[string]string{"user": user}}
+			})),
+	}
+
+	assert.NoError(t, handshake(t, config, "alice", "password🔐🔓"))
+	assert.Error(t, handshake(t, config, "alice", "wrong"))
+	assert.Error(t, handshake(t, config, "mallory", "password🔐🔓"))
+
+	perms, err := config.PasswordCallback(connMetadata("alice"), []byte("password🔐🔓"))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", perms.Extensions["user"])
+
+	_, err = config.PasswordCallback(connMetadata("alice"), []byte("wrong"))
+	assert.Equal(t, ErrInvalidPassword, err)
+
+	_, err = config.PasswordCallback(connMetadata("mallory"), []byte("password🔐🔓"))
+	assert.Equal(t, ErrInvalidPassword, err)
+}
+
+func TestPasswordCallbackThrottle(t *testing.T) {
+	t.Parallel()
+
+	f := portunestest.NewFake()
+	defer f.Close()
+
+	store := mapStore{"alice": portunestest.Hash("password🔐🔓", nil)}
+	th := throttle.New(time.Minute, time.Hour)
+	cb := PasswordCallback(f.Client(), store,
+		WithThrottle(th),
+		WithTimeout(50*time.Millisecond))
+
+	_, err := cb(connMetadata("mallory"), []byte("password🔐🔓"))
+	assert.Equal(t, ErrInvalidPassword, err)
+	assert.NotZero(t, th.Delay("mallory"), "unknown user not throttled")
+
+	_, err = cb(connMetadata("alice"), []byte("wrong"))
+	assert.Equal(t, ErrInvalidPassword, err)
+	assert.NotZero(t, th.Delay("alice"), "failure not throttled")
+
+	// The throttle delay exceeds the timeout.
+	_, err = cb(connMetadata("alice"), []byte("password🔐🔓"))
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	th.Success("alice")
+
+	_, err = cb(connMetadata("alice"), []byte("password🔐🔓"))
+	assert.NoError(t, err)
+	assert.Zero(t, th.Delay("alice"), "success didn't reset throttle")
+}
+
+func TestPasswordCallbackRehash(t *testing.T) {
+	t.Parallel()
+
+	f := portunestest.NewFake()
+	defer f.Close()
+
+	f.SetRehash(true)
+
+	store := mapStore{"alice": portunestest.Hash("password🔐🔓", nil)}
+
+	var rehashed []byte
+	cb := PasswordCallback(f.Client(), store, WithRehashFunc(func(ctx context.Context, user string, oldHash, newHash []byte) error {
+		rehashed = newHash
+		return nil
+	}))
+
+	_, err := cb(connMetadata("alice"), []byte("password🔐🔓"))
+	require.NoError(t, err)
+	assert.NotNil(t, rehashed, "not rehashed")
+}
+
+func TestPasswordCallbackError(t *testing.T) {
+	t.Parallel()
+
+	f := portunestest.NewFake()
+	defer f.Close()
+
+	f.SetError(status.Error(codes.Unavailable, "down for maintenance"))
+
+	var errs []error
+	store := mapStore{"alice": portunestest.Hash("password🔐🔓", nil)}
+	cb := PasswordCallback(f.Client(), store, WithErrorFunc(func(conn ssh.ConnMetadata, err error) {
+		errs = append(errs, err)
+	}))
+
+	_, err := cb(connMetadata("alice"), []byte("password🔐🔓"))
+	assert.Equal(t, codes.Unavailable, status.Code(err), "invalid gRPC status code")
+	assert.Len(t, errs, 1)
+}
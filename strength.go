@@ -0,0 +1,192 @@
+package portunes
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minPatternLen is the shortest run of characters that is
+// treated as a guessable pattern rather than as independent
+// characters.
+const minPatternLen = 3
+
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+}
+
+// userInputTokens splits user inputs, such as a username or
+// email address, into the lower case words a user might
+// reuse in their password.
+func userInputTokens(userInputs []string) []string {
+	var tokens []string
+	add := func(token string) {
+		if utf8.RuneCountInString(token) >= 4 {
+			tokens = append(tokens, token)
+		}
+	}
+
+	for _, input := range userInputs {
+		input = toLower(input)
+		add(input)
+
+		if at := strings.LastIndexByte(input, '@'); at >= 0 {
+			add(input[:at])
+		}
+
+		fields := strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(fields) > 1 {
+			for _, field := range fields {
+				add(field)
+			}
+		}
+	}
+
+	return tokens
+}
+
+// toLower maps each rune of s to lower case, so the result
+// always has the same number of runes as s.
+func toLower(s string) string {
+	return strings.Map(unicode.ToLower, s)
+}
+
+// estimateEntropy returns a rough estimate of the strength
+// of password in bits.
+//
+// In the spirit of zxcvbn, the password is split into
+// guessable patterns - user inputs, repeated characters,
+// sequences and keyboard runs - which are charged only a
+// few bits each. The remaining characters are charged by
+// the size of their character class.
+func estimateEntropy(password string, tokens []string) float64 {
+	orig := []rune(password)
+
+	// Lower case rune by rune so that runes and orig always
+	// line up, whatever case mapping applies.
+	runes := make([]rune, len(orig))
+	for i, r := range orig {
+		runes[i] = unicode.ToLower(r)
+	}
+
+	// Tokens longer than the password can never match.
+	var tokenRunes [][]rune
+	for _, token := range tokens {
+		if len(token) <= len(password) {
+			tokenRunes = append(tokenRunes, []rune(token))
+		}
+	}
+
+	var bits float64
+	for i := 0; i < len(runes); {
+		n, patternBits := matchPattern(runes[i:], tokenRunes, len(tokens))
+		if n >= minPatternLen {
+			bits += patternBits
+			i += n
+			continue
+		}
+
+		bits += math.Log2(classSize(orig[i]))
+		i++
+	}
+
+	return bits
+}
+
+// matchPattern returns the length and cost in bits of the
+// longest pattern at the start of runes. numTokens is the
+// number of user input tokens, including any that were
+// omitted from tokens for being too long to match.
+//
+// Only as many runes as each pattern spans are examined, so
+// that estimating the entropy of a long password stays
+// roughly linear.
+func matchPattern(runes []rune, tokens [][]rune, numTokens int) (n int, bits float64) {
+	consider := func(m int, b float64) {
+		if m > n {
+			n, bits = m, b
+		}
+	}
+
+	for _, token := range tokens {
+		if hasRunePrefix(runes, token) {
+			consider(len(token), math.Log2(float64(2*numTokens)))
+		}
+	}
+
+	// Repeated characters: aaaa.
+	m := 1
+	for m < len(runes) && runes[m] == runes[0] {
+		m++
+	}
+	consider(m, math.Log2(classSize(runes[0]))+math.Log2(float64(m)))
+
+	// Sequences: abcd, 9876.
+	if len(runes) > 1 {
+		if delta := runes[1] - runes[0]; delta == 1 || delta == -1 {
+			m := 2
+			for m < len(runes) && runes[m]-runes[m-1] == delta {
+				m++
+			}
+			consider(m, math.Log2(classSize(runes[0]))+math.Log2(float64(m))+1)
+		}
+	}
+
+	// Keyboard runs: qwerty, lkjh.
+	for _, row := range keyboardRows {
+		for _, r := range [...]string{row, reverse(row)} {
+			m := 0
+			for m < len(runes) && strings.Contains(r, string(runes[:m+1])) {
+				m++
+			}
+			consider(m, math.Log2(float64(len(r)))+math.Log2(float64(m))+1)
+		}
+	}
+
+	return n, bits
+}
+
+// hasRunePrefix reports whether runes begins with prefix.
+func hasRunePrefix(runes, prefix []rune) bool {
+	if len(prefix) > len(runes) {
+		return false
+	}
+
+	for i, r := range prefix {
+		if runes[i] != r {
+			return false
+		}
+	}
+
+	return true
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+
+	return string(r)
+}
+
+// classSize returns the number of characters in the
+// character class r belongs to.
+func classSize(r rune) float64 {
+	switch {
+	case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		return 26
+	case '0' <= r && r <= '9':
+		return 10
+	case r < utf8.RuneSelf:
+		return 33
+	default:
+		return 100
+	}
+}
@@ -0,0 +1,243 @@
+// Package throttle slows down repeated failed login
+// attempts against a single account.
+//
+// Each failure doubles the delay imposed on the next
+// attempt for that account, up to a maximum. A success, or
+// going long enough without a failure, resets it.
+//
+// Attempts are also spaced apart from each other, so that
+// concurrent attempts against an account are let through
+// one at a time rather than all at once.
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxEntries bounds the number of accounts tracked. Expired
+// entries are swept once it's reached, and the oldest entry
+// is evicted if none have expired.
+const maxEntries = 10000
+
+type entry struct {
+	failures uint
+	last     time.Time // of the last failure
+
+	// next is the earliest time the next attempt may start,
+	// as reserved by Wait.
+	next time.Time
+}
+
+// Throttle tracks failed attempts per account. It is safe
+// for concurrent use.
+type Throttle struct {
+	base, max time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*entry
+
+	now func() time.Time
+}
+
+// New returns a Throttle that delays an account by base
+// after its first failure, doubling with each further
+// failure up to max. An account is forgotten once max has
+// passed since its last failure.
+//
+// Attempts that overlap are spaced apart by the current
+// delay, or by base if the account has no failures.
+func New(base, max time.Duration) *Throttle {
+	if base <= 0 || max < base {
+		panic("throttle: invalid delays")
+	}
+
+	return &Throttle{
+		base: base,
+		max:  max,
+
+		entries: make(map[string]*entry),
+
+		now: time.Now,
+	}
+}
+
+// delay returns the delay imposed by the failures of e.
+func (t *Throttle) delay(e *entry) time.Duration {
+	if e.failures == 0 {
+		return 0
+	}
+
+	delay := t.base
+	for i := uint(1); i < e.failures && delay < t.max; i++ {
+		delay *= 2
+	}
+	if delay > t.max {
+		delay = t.max
+	}
+
+	return delay
+}
+
+// start returns the earliest time the next attempt for e
+// may start.
+func (t *Throttle) start(e *entry, now time.Time) time.Time {
+	start := now
+	if at := e.last.Add(t.delay(e)); e.failures > 0 && at.After(start) {
+		start = at
+	}
+	if e.next.After(start) {
+		start = e.next
+	}
+
+	return start
+}
+
+// Delay returns the remaining time an attempt for account
+// should be delayed by.
+func (t *Throttle) Delay(account string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[account]
+	if !ok {
+		return 0
+	}
+
+	now := t.now()
+	return t.start(e, now).Sub(now)
+}
+
+// Wait blocks until an attempt for account is allowed or
+// ctx is done, in which case it returns ctx.Err().
+//
+// Wait reserves the attempt, so that any other attempt for
+// account waits until this one's delay has passed again.
+func (t *Throttle) Wait(ctx context.Context, account string) error {
+	t.mu.Lock()
+
+	now := t.now()
+
+	e, ok := t.entries[account]
+	if !ok {
+		e = t.add(account, now)
+	}
+
+	start := t.start(e, now)
+
+	spacing := t.delay(e)
+	if spacing == 0 {
+		spacing = t.base
+	}
+
+	prev, next := e.next, start.Add(spacing)
+	e.next = next
+
+	t.mu.Unlock()
+
+	delay := start.Sub(now)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Give up the reservation unless another attempt has
+	// since been made.
+	t.mu.Lock()
+	if t.entries[account] == e && e.next.Equal(next) {
+		e.next = prev
+	}
+	t.mu.Unlock()
+
+	return ctx.Err()
+}
+
+// Failure records a failed attempt for account.
+func (t *Throttle) Failure(account string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+
+	e, ok := t.entries[account]
+	if !ok {
+		e = t.add(account, now)
+	} else if e.failures > 0 && now.Sub(e.last) >= t.max {
+		// Earlier failures have been forgotten.
+		e.failures = 0
+	}
+
+	e.failures++
+	e.last = now
+}
+
+// Success forgets any failed attempts for account.
+func (t *Throttle) Success(account string) {
+	t.mu.Lock()
+	delete(t.entries, account)
+	t.mu.Unlock()
+}
+
+// add adds a new entry for account, first making room for
+// it if there are already maxEntries. t.mu must be held.
+func (t *Throttle) add(account string, now time.Time) *entry {
+	if len(t.entries) >= maxEntries {
+		t.sweep(now)
+	}
+
+	if len(t.entries) >= maxEntries {
+		t.evictOldest()
+	}
+
+	e := new(entry)
+	t.entries[account] = e
+	return e
+}
+
+// expired reports whether e no longer affects any attempt.
+func (t *Throttle) expired(e *entry, now time.Time) bool {
+	return now.Sub(e.last) >= t.max && !now.Before(e.next)
+}
+
+// sweep removes accounts whose last failure was at least
+// max ago and that have no reserved attempts. t.mu must be
+// held.
+func (t *Throttle) sweep(now time.Time) {
+	for account, e := range t.entries {
+		if t.expired(e, now) {
+			delete(t.entries, account)
+		}
+	}
+}
+
+// evictOldest removes the account whose last failure, or
+// reserved attempt if later, was longest ago. t.mu must be
+// held.
+func (t *Throttle) evictOldest() {
+	var (
+		oldest   string
+		oldestAt time.Time
+		found    bool
+	)
+	for account, e := range t.entries {
+		at := e.last
+		if e.next.After(at) {
+			at = e.next
+		}
+
+		if !found || at.Before(oldestAt) {
+			oldest, oldestAt, found = account, at, true
+		}
+	}
+
+	delete(t.entries, oldest)
+}
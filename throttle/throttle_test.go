@@ -0,0 +1,176 @@
+package throttle
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func TestDelay(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{time.Unix(1e9, 0)}
+
+	th := New(time.Second, 10*time.Second)
+	th.now = clock.now
+
+	assert.Equal(t, time.Duration(0), th.Delay("alice"))
+
+	for _, expect := range []time.Duration{1, 2, 4, 8, 10, 10} {
+		th.Failure("alice")
+		assert.Equal(t, expect*time.Second, th.Delay("alice"))
+	}
+
+	assert.Equal(t, time.Duration(0), th.Delay("bob"), "other account delayed")
+
+	clock.t = clock.t.Add(3 * time.Second)
+	assert.Equal(t, 7*time.Second, th.Delay("alice"))
+
+	clock.t = clock.t.Add(7 * time.Second)
+	assert.Equal(t, time.Duration(0), th.Delay("alice"))
+
+	// The account is forgotten after max has passed.
+	th.Failure("alice")
+	assert.Equal(t, time.Second, th.Delay("alice"))
+
+	th.Failure("alice")
+	th.Success("alice")
+	assert.Equal(t, time.Duration(0), th.Delay("alice"))
+}
+
+func TestSweep(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{time.Unix(1e9, 0)}
+
+	th := New(time.Second, 10*time.Second)
+	th.now = clock.now
+
+	for i := 0; i < maxEntries; i++ {
+		th.Failure(strconv.Itoa(i))
+	}
+
+	clock.t = clock.t.Add(10 * time.Second)
+	th.Failure("alice")
+
+	assert.Len(t, th.entries, 1)
+}
+
+func TestEvictOldest(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{time.Unix(1e9, 0)}
+
+	th := New(time.Second, 10*time.Second)
+	th.now = clock.now
+
+	for i := 0; i < maxEntries; i++ {
+		th.Failure(strconv.Itoa(i))
+		clock.t = clock.t.Add(time.Millisecond)
+	}
+
+	// Nothing has expired, so the oldest entry is evicted.
+	th.Failure("alice")
+
+	assert.Len(t, th.entries, maxEntries)
+	assert.NotContains(t, th.entries, "0")
+	assert.Contains(t, th.entries, "1")
+	assert.Contains(t, th.entries, "alice")
+
+	assert.NoError(t, th.Wait(context.Background(), "bob"))
+	assert.Len(t, th.entries, maxEntries)
+	assert.NotContains(t, th.entries, "1")
+}
+
+func TestWait(t *testing.T) {
+	t.Parallel()
+
+	th := New(20*time.Millisecond, time.Second)
+
+	assert.NoError(t, th.Wait(context.Background(), "alice"))
+
+	th.Failure("alice")
+
+	start := time.Now()
+	assert.NoError(t, th.Wait(context.Background(), "alice"))
+	assert.True(t, time.Since(start) >= 15*time.Millisecond, "didn't wait")
+
+	th.Failure("alice")
+	th.Failure("alice")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, th.Wait(ctx, "alice"))
+}
+
+func TestWaitConcurrent(t *testing.T) {
+	t.Parallel()
+
+	const (
+		base     = 50 * time.Millisecond
+		attempts = 4
+	)
+
+	for _, failures := range []int{0, 1} {
+		th := New(base, time.Minute)
+		for i := 0; i < failures; i++ {
+			th.Failure("alice")
+		}
+
+		begin := time.Now()
+
+		starts := make(chan time.Duration, attempts)
+		var wg sync.WaitGroup
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				assert.NoError(t, th.Wait(context.Background(), "alice"))
+				starts <- time.Since(begin)
+			}()
+		}
+		wg.Wait()
+		close(starts)
+
+		var sorted []time.Duration
+		for start := range starts {
+			sorted = append(sorted, start)
+		}
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		// Each attempt must start at least base after the one
+		// before it, less some slack for timer granularity.
+		for i := 1; i < len(sorted); i++ {
+			assert.True(t, sorted[i]-sorted[i-1] >= base*8/10,
+				"attempts %d and %d started %s apart with %d failures", i-1, i, sorted[i]-sorted[i-1], failures)
+		}
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	t.Parallel()
+
+	th := New(time.Hour, time.Hour)
+	th.Failure("alice")
+
+	before := th.Delay("alice")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, th.Wait(ctx, "alice"))
+
+	// The canceled attempt gave up its reservation.
+	assert.True(t, th.Delay("alice") <= before, "canceled attempt extended the delay")
+}
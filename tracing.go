@@ -0,0 +1,80 @@
+package portunes
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const instrumentationName = "go.tmthrgd.dev/portunes"
+
+// metadataCarrier adapts grpc metadata to the
+// propagation.TextMapCarrier interface.
+type metadataCarrier metadata.MD
+
+func (mc metadataCarrier) Get(key string) string {
+	if vals := metadata.MD(mc).Get(key); len(vals) > 0 {
+		return vals[0]
+	}
+
+	return ""
+}
+
+func (mc metadataCarrier) Set(key, value string) {
+	metadata.MD(mc).Set(key, value)
+}
+
+func (mc metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc))
+	for key := range mc {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// extractTraceContext returns a context carrying any trace
+// context propagated in the incoming grpc metadata.
+func extractTraceContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+}
+
+// injectTraceContext returns a context with the trace
+// context from ctx added to the outgoing grpc metadata.
+func injectTraceContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = make(metadata.MD)
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func paramsAttributes(time, memory uint32, threads uint8) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int64("argon2.time", int64(time)),
+		attribute.Int64("argon2.memory", int64(memory)),
+		attribute.Int("argon2.threads", int(threads)),
+	}
+}
+
+// spanError records err, which may be a grpc status error,
+// against span and returns it unchanged.
+func spanError(span trace.Span, err error) error {
+	span.RecordError(err)
+	span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	return err
+}
@@ -0,0 +1,70 @@
+package portunes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	csr, ssr := tracetest.NewSpanRecorder(), tracetest.NewSpanRecorder()
+
+	c, _, stop := testingClientWithOptions(
+		[]ClientOption{WithClientTracerProvider(
+			sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(csr)))},
+		WithTracerProvider(
+			sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(ssr))))
+	defer stop()
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	_, _, err = c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	clientSpans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range csr.Ended() {
+		assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+		clientSpans[span.Name()] = span
+	}
+
+	require.Len(t, clientSpans, 2)
+
+	serverSpans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range ssr.Ended() {
+		if span.SpanKind() == trace.SpanKindServer {
+			serverSpans[span.Name()] = span
+		}
+	}
+
+	require.Len(t, serverSpans, 2)
+
+	for name, cspan := range clientSpans {
+		sspan, ok := serverSpans[name]
+		require.True(t, ok, "missing server span for %s", name)
+
+		assert.Equal(t, cspan.SpanContext().TraceID(), sspan.SpanContext().TraceID(), "trace ID")
+		assert.Equal(t, cspan.SpanContext().SpanID(), sspan.Parent().SpanID(), "parent span ID")
+	}
+
+	var argon2Spans int
+	for _, span := range ssr.Ended() {
+		if span.Name() != "portunes.argon2id" {
+			continue
+		}
+
+		argon2Spans++
+		assert.Contains(t, span.Attributes(), paramsAttributes(1, 64*1024, 2)[1])
+	}
+
+	assert.Equal(t, 2, argon2Spans, "argon2id spans")
+}
@@ -0,0 +1,46 @@
+package portunes
+
+import "go.tmthrgd.dev/portunes/internal/argon2"
+
+// Variant is an Argon2 variant. Its value is one more than
+// the type from the Argon2 specification, so that the zero
+// Variant is invalid rather than Argon2d.
+type Variant uint8
+
+const (
+	// Argon2d uses data-dependent memory access. It is
+	// the most resistant to GPU cracking, but is
+	// vulnerable to side-channel attacks and so should
+	// only be used to verify imported hashes.
+	Argon2d Variant = Variant(argon2.Argon2d) + 1
+	// Argon2i uses data-independent memory access.
+	Argon2i Variant = Variant(argon2.Argon2i) + 1
+	// Argon2id is a hybrid of Argon2i and Argon2d. It is
+	// the recommended variant and is used by default.
+	Argon2id Variant = Variant(argon2.Argon2id) + 1
+)
+
+// valid reports whether v is a known variant.
+func (v Variant) valid() bool {
+	return v >= Argon2d && v <= Argon2id
+}
+
+// mode returns the type of v from the Argon2 specification,
+// which is also how it's encoded in hashes. v must be
+// valid.
+func (v Variant) mode() argon2.Mode {
+	return argon2.Mode(v - 1)
+}
+
+func (v Variant) String() string {
+	switch v {
+	case Argon2d:
+		return "argon2d"
+	case Argon2i:
+		return "argon2i"
+	case Argon2id:
+		return "argon2id"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,135 @@
+package portunes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariants(t *testing.T) {
+	t.Parallel()
+
+	for _, variant := range []Variant{Argon2d, Argon2i, Argon2id} {
+		variant := variant // capture range variable
+
+		t.Run(variant.String(), func(t *testing.T) {
+			t.Parallel()
+
+			c, _, stop := testingClient(WithVariant(variant))
+			defer stop()
+
+			hash, err := c.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+			require.NoError(t, err)
+
+			t.Logf("%d:%02x", len(hash), hash)
+
+			d, err := decodeHash(nil, hash)
+			require.NoError(t, err, "decodeHash")
+			assert.Equal(t, variant, d.variant, "variant")
+
+			valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+			require.NoError(t, err)
+
+			assert.True(t, valid, "valid")
+			assert.False(t, rehash, "rehash")
+
+			valid, _, err = c.Verify(context.Background(), "wrong🔑📋", []byte("🔑📋"), hash)
+			require.NoError(t, err)
+
+			assert.False(t, valid, "valid")
+		})
+	}
+}
+
+func TestVariantZero(t *testing.T) {
+	t.Parallel()
+
+	var v Variant
+	assert.Equal(t, "unknown", v.String())
+
+	assert.PanicsWithValue(t, "portunes: invalid argon2 variant", func() {
+		WithVariant(v)
+	})
+
+	assert.PanicsWithValue(t, "portunes: invalid argon2 variant", func() {
+		appendParams(nil, &params{
+			vers:    paramsV5,
+			time:    1,
+			memory:  64 * 1024,
+			threads: 1,
+			saltLen: defaultSaltLen,
+			tagLen:  defaultTagLen,
+		})
+	})
+}
+
+func TestVariantRehash(t *testing.T) {
+	t.Parallel()
+
+	c1, _, stop1 := testingClient(WithVariant(Argon2i))
+	defer stop1()
+
+	hash, err := c1.Hash(context.Background(), "password🔐🔓", []byte("🔑📋"))
+	require.NoError(t, err)
+
+	c2, _, stop2 := testingClient()
+	defer stop2()
+
+	valid, rehash, err := c2.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+	require.NoError(t, err)
+
+	assert.True(t, valid, "valid")
+	assert.True(t, rehash, "rehash")
+}
+
+func TestFromPHC(t *testing.T) {
+	t.Parallel()
+
+	c, _, stop := testingClient()
+	defer stop()
+
+	// These are from the Argon2 reference implementation.
+	for _, phc := range []string{
+		"$argon2i$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA",
+		"$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+	} {
+		hash, err := FromPHC(phc)
+		require.NoError(t, err, phc)
+
+		t.Logf("%d:%02x", len(hash), hash)
+
+		valid, rehash, err := c.Verify(context.Background(), "password", nil, hash)
+		require.NoError(t, err)
+
+		assert.True(t, valid, "valid")
+		assert.True(t, rehash, "rehash")
+
+		valid, _, err = c.Verify(context.Background(), "wrong", nil, hash)
+		require.NoError(t, err)
+
+		assert.False(t, valid, "valid")
+	}
+}
+
+func TestFromPHCInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, phc := range []string{
+		"",
+		"$argon2i$m=65536,t=2,p=1$c29tZXNhbHQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA",
+		"$argon2i$v=16$m=65536,t=2,p=1$c29tZXNhbHQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA",
+		"$argon2x$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA",
+		"$argon2i$v=19$m=65536,t=2$c29tZXNhbHQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA",
+		"$argon2i$v=19$m=65536,t=2,p=256$c29tZXNhbHQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA",
+		"$argon2i$v=19$m=65536,t=0,p=1$c29tZXNhbHQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA",
+		"$argon2i$v=19$m=65536,t=2,p=1,data=c29tZQ$c29tZXNhbHQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA",
+		"$argon2i$v=19$m=65536,t=2,p=1$c29tZQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA",
+		"$argon2i$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$wWKI",
+		"$argon2i$v=19$m=65536,t=2,p=1$c29tZXNhbHQ=$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA",
+	} {
+		_, err := FromPHC(phc)
+		assert.Error(t, err, phc)
+	}
+}
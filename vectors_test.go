@@ -0,0 +1,95 @@
+package portunes
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/status"
+)
+
+type vectorParams struct {
+	Version       uint8         `json:"version"`
+	Variant       uint8         `json:"variant"` // as encoded
+	Time          uint32        `json:"time"`
+	Memory        uint32        `json:"memory"`
+	Threads       uint8         `json:"threads"`
+	Normalization Normalization `json:"normalization"`
+	SaltLength    uint32        `json:"salt_length"`
+	TagLength     uint32        `json:"tag_length"`
+	Legacy        legacyScheme  `json:"legacy"`
+	LegacySetting string        `json:"legacy_setting"`
+}
+
+type vector struct {
+	Comment  string        `json:"comment"`
+	Password string        `json:"password"`
+	Pepper   string        `json:"pepper"`
+	Hash     string        `json:"hash"`
+	Valid    bool          `json:"valid"`
+	Params   *vectorParams `json:"params"`
+	Error    string        `json:"error"`
+}
+
+func TestVectorsFile(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("testdata/vectors.json")
+	require.NoError(t, err)
+	defer f.Close()
+
+	var vectors []vector
+	require.NoError(t, json.NewDecoder(f).Decode(&vectors))
+
+	for _, v := range vectors {
+		v := v // capture range variable
+
+		t.Run(v.Comment, func(t *testing.T) {
+			t.Parallel()
+
+			hash, err := hex.DecodeString(v.Hash)
+			require.NoError(t, err, "invalid test vector hash")
+
+			pepper, err := hex.DecodeString(v.Pepper)
+			require.NoError(t, err, "invalid test vector pepper")
+
+			c, _, stop := testingClient()
+			defer stop()
+
+			valid, _, err := c.Verify(context.Background(), v.Password, pepper, hash)
+			if v.Error != "" {
+				st, ok := status.FromError(err)
+				require.True(t, ok, "error is not a status")
+				assert.Equal(t, v.Error, st.Code().String(), "invalid gRPC status code")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, v.Valid, valid, "valid")
+
+			d, err := decodeHash(nil, hash)
+			require.NoError(t, err, "decodeHash")
+			assert.Equal(t, params{
+				vers: v.Params.Version,
+
+				time:    v.Params.Time,
+				memory:  v.Params.Memory,
+				threads: v.Params.Threads,
+
+				norm: v.Params.Normalization,
+
+				saltLen: v.Params.SaltLength,
+				tagLen:  v.Params.TagLength,
+
+				variant: Variant(v.Params.Variant) + 1,
+
+				legacy:        v.Params.Legacy,
+				legacySetting: v.Params.LegacySetting,
+			}, d.params, "params")
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package portunes
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	pb "go.tmthrgd.dev/portunes/internal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s pbServer) WrapLegacy(ctx context.Context, req *pb.WrapLegacyRequest) (*pb.HashResponse, error) {
+	ctx, span := s.tracer.Start(extractTraceContext(ctx), "portunes.Hasher/WrapLegacy",
+		trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	if s.hardened {
+		defer wipe(req.LegacyHash)
+		defer wipe(req.Pepper)
+	}
+
+	if err := s.checkLimits(nil, req.Pepper); err != nil {
+		return nil, spanError(span, err)
+	}
+
+	scheme, setting, output, ok := splitLegacyHash(req.LegacyHash)
+	if !ok {
+		return nil, spanError(span, status.Error(codes.InvalidArgument, "unsupported legacy hash"))
+	}
+
+	p := s.hashParams()
+
+	// The legacy hash was computed over the password as
+	// given, so it mustn't be normalized.
+	p.norm = NormalizeNone
+	p.legacy, p.legacySetting = scheme, string(setting)
+	p.setVersion()
+
+	salt, err := newSalt(&p)
+	if err != nil {
+		return nil, spanError(span, status.Error(codes.Internal, err.Error()))
+	}
+
+	mem, err := s.admit(ctx, &p, nil)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer s.release(mem)
+
+	tag := s.deriver(mem).argon2Key(ctx, output, req.Pepper, salt, &p)
+	hash := encodeHash(&p, salt, tag)
+
+	if s.hardened {
+		wipe(tag)
+	}
+
+	hash, err = s.seal(hash)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	return &pb.HashResponse{
+		Hash: hash,
+	}, nil
+}
+
+// WrapLegacy wraps a hash from a legacy password hashing
+// scheme in an onion hash, which derives an argon2 tag
+// from the legacy hash. This strengthens existing hashes
+// without needing the user's password.
+//
+// bcrypt and MD5-crypt, both "$1$" and Apache's "$apr1$",
+// hashes are supported.
+//
+// The returned hash can be passed to Verify with the
+// user's password and pepper, and will always be marked for
+// rehashing so the user is moved to a plain hash when they
+// next log in.
+//
+// opts can be used to provide grpc.CallOption's to the
+// underlying connection.
+func (c *Client) WrapLegacy(ctx context.Context, legacyHash, pepper []byte, opts ...grpc.CallOption) ([]byte, error) {
+	ctx, span := c.startSpan(ctx, "portunes.Hasher/WrapLegacy")
+	defer span.End()
+
+	resp, err := c.pc.WrapLegacy(ctx, &pb.WrapLegacyRequest{
+		LegacyHash: legacyHash,
+		Pepper:     pepper,
+	}, disableCompression(opts)...)
+	if err != nil {
+		return nil, spanError(span, clientError(err))
+	}
+
+	return resp.Hash, nil
+}
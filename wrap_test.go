@@ -0,0 +1,185 @@
+package portunes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWrapLegacy(t *testing.T) {
+	t.Parallel()
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("password🔐🔓"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	for _, legacy := range []struct {
+		name string
+		hash []byte
+	}{
+		{"bcrypt", bcryptHash},
+		{"md5crypt", []byte("$1$8sFt66rZ$Xd2dBKsS3mGAlqTyOk3Vx1")},
+		{"md5crypt", []byte("$apr1$8sFt66rZ$/2KB/ChEot7Ge/1n068od/")},
+	} {
+		for _, sopt := range [][]ServerOption{
+			nil,
+			{WithNormalization(NormalizeNFKC)},
+			{WithEnvelopeKeys(testEnvelopeKey1.ID, testEnvelopeKey1)},
+		} {
+			c, _, stop := testingClient(sopt...)
+			defer stop()
+
+			hash, err := c.WrapLegacy(context.Background(), legacy.hash, []byte("🔑📋"))
+			require.NoError(t, err)
+
+			t.Logf("%d:%02x", len(hash), hash)
+
+			if info, err := Inspect(hash); assert.NoError(t, err) && !info.Encrypted {
+				assert.Equal(t, legacy.name, info.Legacy, "Legacy")
+			}
+
+			valid, rehash, err := c.Verify(context.Background(), "password🔐🔓", []byte("🔑📋"), hash)
+			require.NoError(t, err)
+
+			assert.True(t, valid, "valid")
+			assert.True(t, rehash, "rehash")
+
+			valid, _, err = c.Verify(context.Background(), "wrong🔐🔓", []byte("🔑📋"), hash)
+			require.NoError(t, err)
+			assert.False(t, valid, "valid")
+
+			valid, _, err = c.Verify(context.Background(), "password🔐🔓", []byte("📋🔑"), hash)
+			require.NoError(t, err)
+			assert.False(t, valid, "valid")
+		}
+	}
+}
+
+func TestWrapLegacyInvalid(t *testing.T) {
+	t.Parallel()
+
+	c, _, stop := testingClient()
+	defer stop()
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", nil)
+	require.NoError(t, err)
+
+	for _, legacy := range []string{
+		"",
+		string(hash),
+		"{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+		"$2a$10$XajjQvNhvvRt5GSeFk1xFeyqRrsxkhBkUiQeg0dt.wU1qD4aFDcg",
+		"$1$8sFt66rZ$Xd2dBKsS3mGAlqTyOk3Vx",
+		"$5$rounds=5000$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZF2w1b4/6",
+		// The cost is above maxBcryptCost.
+		"$2a$13$XajjQvNhvvRt5GSeFk1xFeyqRrsxkhBkUiQeg0dt.wU1qD4aFDcga",
+		"$2a$17$XajjQvNhvvRt5GSeFk1xFeyqRrsxkhBkUiQeg0dt.wU1qD4aFDcga",
+	} {
+		_, err := c.WrapLegacy(context.Background(), []byte(legacy), nil)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+	}
+}
+
+func TestVerifyLegacyCostTooHigh(t *testing.T) {
+	t.Parallel()
+
+	c, _, stop := testingClient()
+	defer stop()
+
+	for cost, valid := range map[string]bool{
+		"12": true,
+		"13": false,
+		"16": false,
+		"31": false,
+	} {
+		// Encode the hash directly as WrapLegacy would refuse
+		// to create it.
+		p := params{
+			time: 1, memory: 8 * 1024, threads: 1,
+			saltLen: defaultSaltLen, tagLen: defaultTagLen,
+			variant: Argon2id,
+
+			legacy:        legacyBcrypt,
+			legacySetting: "$2a$" + cost + "$XajjQvNhvvRt5GSeFk1xFe",
+		}
+		p.setVersion()
+
+		hash := encodeHash(&p, make([]byte, p.saltLen), make([]byte, p.tagLen))
+
+		_, err := Inspect(hash)
+		if valid {
+			assert.NoError(t, err, "cost %s", cost)
+			continue
+		}
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code for cost %s", cost)
+
+		start := time.Now()
+		_, _, err = c.Verify(context.Background(), "password🔐🔓", nil, hash)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code for cost %s", cost)
+		assert.ErrorIs(t, err, ErrHashMalformed)
+		assert.Less(t, int64(time.Since(start)), int64(time.Second), "Verify took too long for cost %s", cost)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	t.Parallel()
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password🔐🔓"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	c, _, stop := testingClient(WithNormalization(NormalizeNFC), WithHashLengths(24, 32))
+	defer stop()
+
+	hash, err := c.Hash(context.Background(), "password🔐🔓", nil)
+	require.NoError(t, err)
+
+	info, err := Inspect(hash)
+	require.NoError(t, err)
+	assert.Equal(t, &HashInfo{
+		Version: paramsV4,
+
+		Variant:       Argon2id,
+		Time:          1,
+		Memory:        64 * 1024,
+		Threads:       2,
+		Normalization: NormalizeNFC,
+
+		SaltLength: 24,
+		TagLength:  32,
+	}, info)
+
+	wrapped, err := c.WrapLegacy(context.Background(), legacy, nil)
+	require.NoError(t, err)
+
+	info, err = Inspect(wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, paramsV6, info.Version)
+	assert.Equal(t, NormalizeNone, info.Normalization)
+	assert.Equal(t, "bcrypt", info.Legacy)
+
+	c2, _, stop2 := testingClient(WithEnvelopeKeys(testEnvelopeKey2.ID, testEnvelopeKey2))
+	defer stop2()
+
+	sealed, err := c2.Hash(context.Background(), "password🔐🔓", nil)
+	require.NoError(t, err)
+
+	info, err = Inspect(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, &HashInfo{
+		Version:   envelopeV,
+		Encrypted: true,
+		KeyID:     testEnvelopeKey2.ID,
+	}, info)
+
+	_, err = Inspect([]byte{0x7f, 0x00})
+	assert.Equal(t, codes.Unimplemented, status.Code(err), "invalid gRPC status code")
+
+	_, err = Inspect(hash[:len(hash)-1])
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid gRPC status code")
+}